@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	sp "safe-paste/safe_paste"
+)
+
+const cliUsage = `Usage:
+  safepaste                      start the GUI
+  safepaste mask [flags] [file...]
+  safepaste unmask [flags] [file...]
+
+Input is read from the files, or from stdin if none are given.
+The result is written to stdout.
+
+Sessions use the encrypted vault next to config.json; the passphrase is read
+from the SAFEPASTE_PASSPHRASE environment variable.
+`
+
+// runCLI runs a headless subcommand. ok is false if args do not name one,
+// in which case the GUI should start.
+func runCLI(args []string, stdin io.Reader, stdout, stderr io.Writer) (code int, ok bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	var err error
+	switch args[0] {
+	case "mask":
+		err = cliMask(args[1:], stdin, stdout, stderr)
+	case "unmask":
+		err = cliUnmask(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, cliUsage)
+		return 0, true
+	default:
+		return 0, false
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0, true
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "safepaste:", err)
+		return 1, true
+	}
+	return 0, true
+}
+
+func cliMask(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("mask", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "config file (default: config.json next to the executable)")
+	mappingPath := fs.String("mapping", "", "write the mapping as JSON to this file")
+	session := fs.String("session", "", "save the mapping as this vault session")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	masker, err := cliMasker(*configPath)
+	if err != nil {
+		return err
+	}
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	result := masker.Mask(input)
+
+	if *mappingPath != "" {
+		data, err := json.MarshalIndent(result.Mapping, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*mappingPath, data, 0600); err != nil {
+			return err
+		}
+	}
+	if *session != "" {
+		vault, err := cliVault()
+		if err != nil {
+			return err
+		}
+		if err := vault.SaveSession(sp.Session{Name: *session, Mapping: result.Mapping}); err != nil {
+			return err
+		}
+	}
+	if *mappingPath == "" && *session == "" && len(result.Mapping) > 0 {
+		fmt.Fprintln(stderr, "safepaste: warning: no --mapping or --session given, output cannot be unmasked")
+	}
+
+	_, err = io.WriteString(stdout, result.MaskedText)
+	return err
+}
+
+func cliUnmask(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("unmask", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	mappingPath := fs.String("mapping", "", "read the mapping from this JSON file")
+	session := fs.String("session", "", "read the mapping from this vault session")
+	configPath := fs.String("config", "", "config file, for the token format (default: config.json next to the executable)")
+	fuzzy := fs.Bool("fuzzy", false, "also restore tokens rewritten by an AI (IP1, ip_1, hostname1s)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var mapping map[string]string
+	switch {
+	case *mappingPath != "" && *session != "":
+		return errors.New("use either --mapping or --session, not both")
+	case *mappingPath != "":
+		data, err := os.ReadFile(*mappingPath)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &mapping); err != nil {
+			return fmt.Errorf("%s: %w", *mappingPath, err)
+		}
+	case *session != "":
+		vault, err := cliVault()
+		if err != nil {
+			return err
+		}
+		sess, err := vault.LoadSession(*session)
+		if err != nil {
+			return err
+		}
+		mapping = sess.Mapping
+	default:
+		return errors.New("unmask needs --mapping or --session")
+	}
+
+	input, err := readInput(fs.Args(), stdin)
+	if err != nil {
+		return err
+	}
+	masker, err := cliMasker(*configPath)
+	if err != nil {
+		return err
+	}
+	opts := sp.UnmaskOptions{Fuzzy: *fuzzy, Scheme: masker.TokenScheme(), Domains: masker.KeptDomains()}
+	unmasked, report := sp.UnmaskTextWithReport(input, mapping, opts)
+	for _, w := range report.Warnings() {
+		fmt.Fprintln(stderr, "safepaste:", w)
+	}
+	_, err = io.WriteString(stdout, unmasked)
+	return err
+}
+
+// cliMasker uses the given config file, or config.json next to the executable
+func cliMasker(configPath string) (*sp.Masker, error) {
+	if configPath == "" {
+		return sp.DefaultMasker()
+	}
+	cfg, err := sp.LoadConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return sp.NewMasker(cfg)
+}
+
+func cliVault() (*sp.Vault, error) {
+	passphrase := os.Getenv("SAFEPASTE_PASSPHRASE")
+	if passphrase == "" {
+		return nil, errors.New("SAFEPASTE_PASSPHRASE is not set")
+	}
+	return sp.OpenVault(sp.DefaultSessionDir(), passphrase)
+}
+
+// readInput concatenates the files, or reads stdin if there are none
+func readInput(files []string, stdin io.Reader) (string, error) {
+	if len(files) == 0 {
+		data, err := io.ReadAll(stdin)
+		return string(data), err
+	}
+	var data []byte
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		data = append(data, b...)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// runTest runs a subcommand with stdin as input and returns its exit code,
+// whether it was one, and its output. Tests chdir to a temporary directory
+// first, so config.json and the sessions folder are read from there.
+func runTest(t *testing.T, args []string, stdin string) (code int, ok bool, stdout, stderr string) {
+	t.Helper()
+	var out, errOut strings.Builder
+	code, ok = runCLI(args, strings.NewReader(stdin), &out, &errOut)
+	return code, ok, out.String(), errOut.String()
+}
+
+func TestRunCLI(t *testing.T) {
+	t.Chdir(t.TempDir())
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		ok     bool
+		stdout string // expected output
+		stderr string // substring of the expected messages
+	}{
+		{"no subcommand", nil, "", 0, false, "", ""},
+		{"unknown subcommand", []string{"-psn_0_12345"}, "", 0, false, "", ""},
+		{"help", []string{"help"}, "", 0, true, cliUsage, ""},
+		{"flag help", []string{"mask", "-h"}, "", 0, true, "", "Usage of mask"},
+		{"unknown flag", []string{"mask", "--nope"}, "", 1, true, "", "flag provided but not defined: -nope"},
+		{"missing file", []string{"mask", "missing.txt"}, "", 1, true, "", "missing.txt"},
+		{"no mapping", []string{"mask"}, "ping 10.0.0.1", 0, true, "ping ip1", "no --mapping or --session given"},
+		{"nothing masked", []string{"mask"}, "hello", 0, true, "hello", ""},
+		{"unmask without mapping", []string{"unmask"}, "ip1", 1, true, "", "unmask needs --mapping or --session"},
+		{"unmask with both", []string{"unmask", "--mapping", "m.json", "--session", "s"}, "ip1", 1, true, "", "not both"},
+		{"unmask missing mapping", []string{"unmask", "--mapping", "m.json"}, "ip1", 1, true, "", "m.json"},
+	}
+	for _, tt := range tests {
+		code, ok, stdout, stderr := runTest(t, tt.args, tt.stdin)
+		if code != tt.code || ok != tt.ok {
+			t.Errorf("%s: runCLI = %d, %v, want %d, %v (stderr %q)", tt.name, code, ok, tt.code, tt.ok, stderr)
+		}
+		if stdout != tt.stdout {
+			t.Errorf("%s: stdout = %q, want %q", tt.name, stdout, tt.stdout)
+		}
+		if tt.stderr == "" && stderr != "" || !strings.Contains(stderr, tt.stderr) {
+			t.Errorf("%s: stderr = %q, want %q", tt.name, stderr, tt.stderr)
+		}
+	}
+}
+
+func TestCLIMappingFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	input := "ssh 10.0.0.1 and 10.0.0.2\n"
+	if err := os.WriteFile("input.txt", []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mapping := filepath.Join(dir, "mapping.json")
+
+	code, _, masked, stderr := runTest(t, []string{"mask", "--mapping", mapping, "input.txt"}, "")
+	if code != 0 || masked != "ssh ip1 and ip2\n" || stderr != "" {
+		t.Fatalf("mask = %d, %q, stderr %q", code, masked, stderr)
+	}
+	info, err := os.Stat(mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Windows has no Unix permission bits
+	if perm := info.Mode().Perm(); runtime.GOOS != "windows" && perm != 0600 {
+		t.Errorf("mapping file permissions = %v, want 0600", perm)
+	}
+
+	code, _, unmasked, stderr := runTest(t, []string{"unmask", "--mapping", mapping}, "IP1 is down, ip2 is up\n")
+	if code != 0 || unmasked != "IP1 is down, 10.0.0.2 is up\n" {
+		t.Errorf("unmask = %d, %q, stderr %q", code, unmasked, stderr)
+	}
+	if !strings.Contains(stderr, "ip1") {
+		t.Errorf("unmask stderr = %q, want ip1 reported missing", stderr)
+	}
+
+	code, _, unmasked, _ = runTest(t, []string{"unmask", "--fuzzy", "--mapping", mapping}, "IP1 is down, ip2 is up\n")
+	if code != 0 || unmasked != "10.0.0.1 is down, 10.0.0.2 is up\n" {
+		t.Errorf("unmask --fuzzy = %d, %q", code, unmasked)
+	}
+}
+
+func TestCLISession(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("SAFEPASTE_PASSPHRASE", "")
+	if code, _, _, stderr := runTest(t, []string{"mask", "--session", "incident 42"}, "ping 10.0.0.1"); code != 1 || !strings.Contains(stderr, "SAFEPASTE_PASSPHRASE is not set") {
+		t.Errorf("mask without passphrase = %d, stderr %q", code, stderr)
+	}
+
+	t.Setenv("SAFEPASTE_PASSPHRASE", "correct horse")
+	code, _, masked, stderr := runTest(t, []string{"mask", "--session", "incident 42"}, "ping 10.0.0.1")
+	if code != 0 || masked != "ping ip1" || stderr != "" {
+		t.Fatalf("mask = %d, %q, stderr %q", code, masked, stderr)
+	}
+	code, _, unmasked, stderr := runTest(t, []string{"unmask", "--session", "incident 42"}, "ip1 answers")
+	if code != 0 || unmasked != "10.0.0.1 answers" {
+		t.Errorf("unmask = %d, %q, stderr %q", code, unmasked, stderr)
+	}
+	if code, _, _, stderr := runTest(t, []string{"unmask", "--session", "incident 43"}, "ip1"); code != 1 || stderr == "" {
+		t.Errorf("unmask of a missing session = %d, stderr %q", code, stderr)
+	}
+
+	t.Setenv("SAFEPASTE_PASSPHRASE", "wrong horse")
+	if code, _, _, stderr := runTest(t, []string{"unmask", "--session", "incident 42"}, "ip1"); code != 1 || !strings.Contains(stderr, "passphrase") {
+		t.Errorf("unmask with the wrong passphrase = %d, stderr %q", code, stderr)
+	}
+}
@@ -2,7 +2,10 @@ module safe-paste
 
 go 1.24.2
 
-require gioui.org v0.9.0
+require (
+	gioui.org v0.9.0
+	golang.org/x/crypto v0.38.0
+)
 
 require (
 	gioui.org/shader v1.0.8 // indirect
@@ -10,5 +13,5 @@ require (
 	golang.org/x/exp/shiny v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
 	golang.org/x/image v0.26.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
-	golang.org/x/text v0.24.0 // indirect
+	golang.org/x/text v0.25.0 // indirect
 )
@@ -1,13 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"log"
 	"math"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
@@ -28,6 +28,11 @@ import (
 const Version = "v1.0.0"
 
 func main() {
+	// Headless mode: safepaste mask|unmask
+	if code, ok := runCLI(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); ok {
+		os.Exit(code)
+	}
+
 	go func() {
 		window := new(app.Window)
 		window.Option(app.Title("SafePaste"))
@@ -77,6 +82,12 @@ func run(window *app.Window) error {
 	var clearUnmaskButton widget.Clickable
 	var themeSwitchButton widget.Clickable
 
+	// Problems found by the last unmask, shown under the unmasked text
+	var unmaskWarnings []string
+
+	// Fuzzy unmask also restores tokens rewritten by the AI (IP1, ip_1, hostname1s)
+	var fuzzyUnmask widget.Bool
+
 	// Animation state
 	var animProgress float32
 	if isDark {
@@ -87,6 +98,92 @@ func run(window *app.Window) error {
 	// Store mapping for unmasking
 	var currentMapping map[string]string
 
+	// Saved sessions, so a reply can still be unmasked after a restart.
+	// They live in an encrypted vault that is unlocked with a passphrase.
+	sessionDir := sp.DefaultSessionDir()
+	var vault *sp.Vault // nil while locked
+	var sessions []sp.Session
+	sessionIndex := -1 // selected entry in sessions, -1 if none
+
+	// Problems are shown in the sessions bar: the GUI build has no console for logs
+	var vaultStatus string   // next to the passphrase while locked
+	var sessionStatus string // next to the session picker, e.g. a failed save
+
+	var passphraseEditor widget.Editor
+	passphraseEditor.SingleLine = true
+	passphraseEditor.Submit = true
+	passphraseEditor.Mask = '•'
+
+	// A new vault asks for the passphrase twice, so a typo cannot lock the user out
+	var confirmEditor widget.Editor
+	confirmEditor.SingleLine = true
+	confirmEditor.Submit = true
+	confirmEditor.Mask = '•'
+
+	var unlockButton widget.Clickable
+
+	var sessionNameEditor widget.Editor
+	sessionNameEditor.SingleLine = true
+
+	var prevSessionButton widget.Clickable
+	var nextSessionButton widget.Clickable
+	var deleteSessionButton widget.Clickable
+
+	selectSession := func(i int) {
+		sessionIndex = i
+		currentMapping = sessions[i].Mapping
+		sessionStatus = ""
+		log.Println("Loaded session", sessions[i].Name, "with", len(currentMapping), "mappings")
+	}
+	// refreshSessions reloads the list and keeps the named session selected
+	refreshSessions := func(name string) {
+		var err error
+		sessions, err = vault.ListSessions()
+		if err != nil {
+			sessionStatus = "Failed to list sessions: " + err.Error()
+			log.Println("Failed to list sessions:", err)
+		}
+		sessionIndex = -1
+		for i, sess := range sessions {
+			if sess.Name == name {
+				sessionIndex = i
+			}
+		}
+	}
+	unlockVault := func() {
+		if !sp.VaultExists(sessionDir) && confirmEditor.Text() != passphraseEditor.Text() {
+			vaultStatus = "Passphrases do not match"
+			confirmEditor.SetText("")
+			return
+		}
+		v, err := sp.OpenVault(sessionDir, passphraseEditor.Text())
+		if err != nil {
+			vaultStatus = err.Error()
+			log.Println("Failed to unlock vault:", err)
+			return
+		}
+		vault = v
+		passphraseEditor.SetText("")
+		confirmEditor.SetText("")
+		vaultStatus = ""
+		sessionStatus = ""
+		// Older versions stored sessions in clear text
+		if imported, err := vault.Import(sp.NewSessionStore(sessionDir)); err != nil {
+			sessionStatus = "Failed to import plain sessions: " + err.Error()
+			log.Println("Failed to import plain sessions:", err)
+		} else if imported > 0 {
+			log.Println("Encrypted", imported, "plain sessions")
+		}
+		if removed, err := vault.PruneSessions(cfg.SessionTTL()); err != nil {
+			sessionStatus = "Failed to clean up sessions: " + err.Error()
+			log.Println("Failed to clean up sessions:", err)
+		} else if removed > 0 {
+			log.Println("Removed", removed, "expired sessions")
+		}
+		refreshSessions("")
+		log.Println("Vault unlocked with", len(sessions), "sessions")
+	}
+
 	for {
 		e := window.Event()
 		switch e := e.(type) {
@@ -159,10 +256,35 @@ func run(window *app.Window) error {
 										layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 											if maskButton.Clicked(gtx) {
 												input := inputEditor.Text()
-												result := sp.MaskTextWithMapping(input)
-												outputEditor.SetText(result.MaskedText)
-												currentMapping = result.Mapping
-												log.Println("Masked. Mapping size:", len(currentMapping))
+												masker, err := sp.DefaultMasker()
+												if err != nil {
+													outputEditor.SetText("Config error: " + err.Error())
+													currentMapping = nil
+													log.Println("Invalid config:", err)
+												} else {
+													result := masker.Mask(input)
+													outputEditor.SetText(result.MaskedText)
+													currentMapping = result.Mapping
+													log.Println("Masked. Mapping size:", len(currentMapping))
+
+													// Every mask is a new session, so earlier replies can still be unmasked
+													name := strings.TrimSpace(sessionNameEditor.Text())
+													if name == "" {
+														name = sp.NewSessionName(time.Now())
+													}
+													name = sp.UniqueSessionName(name, sessions)
+													if vault == nil {
+														vaultStatus = "Session not saved: unlock the vault to keep it"
+														log.Println("Vault locked, session not saved")
+													} else if err := vault.SaveSession(sp.Session{Name: name, Mapping: currentMapping}); err != nil {
+														sessionStatus = "Session not saved: " + err.Error()
+														log.Println("Failed to save session:", err)
+													} else {
+														sessionNameEditor.SetText("")
+														sessionStatus = ""
+														refreshSessions(name)
+													}
+												}
 											}
 											btn := material.Button(th, &maskButton, "Mask →")
 											return layout.Inset{Bottom: unit.Dp(8)}.Layout(gtx, btn.Layout)
@@ -181,6 +303,8 @@ func run(window *app.Window) error {
 												inputEditor.SetText("")
 												outputEditor.SetText("")
 												currentMapping = nil
+												sessionNameEditor.SetText("")
+												sessionIndex = -1
 												log.Println("Cleared masked section")
 											}
 											btn := material.Button(th, &clearMaskButton, "Clear")
@@ -249,9 +373,18 @@ func run(window *app.Window) error {
 											if unmaskButton.Clicked(gtx) {
 												aiInput := aiInputEditor.Text()
 												if currentMapping != nil {
-													unmasked := sp.UnmaskText(aiInput, currentMapping)
+													opts := sp.UnmaskOptions{Fuzzy: fuzzyUnmask.Value}
+													if masker, err := sp.DefaultMasker(); err == nil {
+														opts.Scheme = masker.TokenScheme()
+														opts.Domains = masker.KeptDomains()
+													}
+													unmasked, report := sp.UnmaskTextWithReport(aiInput, currentMapping, opts)
 													aiOutputEditor.SetText(unmasked)
-													log.Println("Unmasked with", len(currentMapping), "mappings")
+													unmaskWarnings = report.Warnings()
+													log.Println("Unmasked", len(report.Restored), "of", len(currentMapping), "mappings")
+													for _, w := range unmaskWarnings {
+														log.Println(w)
+													}
 												} else {
 													log.Println("No mapping available. Mask text first!")
 												}
@@ -259,6 +392,10 @@ func run(window *app.Window) error {
 											btn := material.Button(th, &unmaskButton, "Unmask →")
 											return layout.Inset{Bottom: unit.Dp(8)}.Layout(gtx, btn.Layout)
 										}),
+										layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+											cb := material.CheckBox(th, &fuzzyUnmask, "Fuzzy")
+											return layout.Inset{Bottom: unit.Dp(8)}.Layout(gtx, cb.Layout)
+										}),
 										layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 											if copyUnmaskedButton.Clicked(gtx) {
 												text := aiOutputEditor.Text()
@@ -272,6 +409,7 @@ func run(window *app.Window) error {
 											if clearUnmaskButton.Clicked(gtx) {
 												aiInputEditor.SetText("")
 												aiOutputEditor.SetText("")
+												unmaskWarnings = nil
 												log.Println("Cleared unmasked section")
 											}
 											btn := material.Button(th, &clearUnmaskButton, "Clear")
@@ -280,9 +418,7 @@ func run(window *app.Window) error {
 										}),
 										layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 											if settingsButton.Clicked(gtx) {
-												exePath, _ := os.Executable()
-												exeDir := filepath.Dir(exePath)
-												configPath := filepath.Join(exeDir, "config.json")
+												configPath := sp.ConfigPath()
 												if runtime.GOOS == "windows" {
 													exec.Command("notepad.exe", configPath).Start()
 												} else {
@@ -315,10 +451,178 @@ func run(window *app.Window) error {
 											return layout.UniformInset(unit.Dp(8)).Layout(gtx, ed.Layout)
 										})
 									}),
+									// Warnings from the last unmask: unknown, missing and fuzzy tokens
+									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+										if len(unmaskWarnings) == 0 {
+											return layout.Dimensions{}
+										}
+										lbl := material.Body2(th, strings.Join(unmaskWarnings, "\n"))
+										lbl.Color = color.NRGBA{R: 0xE0, G: 0x8A, B: 0x00, A: 0xFF}
+										lbl.MaxLines = 6
+										return layout.Inset{Top: unit.Dp(8)}.Layout(gtx, lbl.Layout)
+									}),
 								)
 							}),
 						)
 					}),
+					// Session picker
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if vault == nil {
+							if unlockButton.Clicked(gtx) {
+								unlockVault()
+							}
+							for {
+								ev, ok := passphraseEditor.Update(gtx)
+								if !ok {
+									break
+								}
+								if _, ok := ev.(widget.SubmitEvent); ok {
+									unlockVault()
+								}
+							}
+							for {
+								ev, ok := confirmEditor.Update(gtx)
+								if !ok {
+									break
+								}
+								if _, ok := ev.(widget.SubmitEvent); ok {
+									unlockVault()
+								}
+							}
+
+							label := "Unlock"
+							info := vaultStatus
+							creating := !sp.VaultExists(sessionDir)
+							if creating {
+								label = "Create vault"
+								if info == "" {
+									info = "Choose a passphrase to keep sessions encrypted"
+								}
+							}
+							return layout.Inset{Top: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+								return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+										return layout.Inset{Right: unit.Dp(8)}.Layout(gtx, material.Body1(th, "Sessions").Layout)
+									}),
+									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+										gtx.Constraints.Min.X = gtx.Dp(unit.Dp(240))
+										gtx.Constraints.Max.X = gtx.Constraints.Min.X
+										border := widget.Border{
+											Color:        th.Fg,
+											CornerRadius: unit.Dp(8),
+											Width:        unit.Dp(1),
+										}
+										return border.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+											ed := material.Editor(th, &passphraseEditor, "Vault passphrase")
+											ed.TextSize = unit.Sp(14)
+											return layout.UniformInset(unit.Dp(8)).Layout(gtx, ed.Layout)
+										})
+									}),
+									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+										if !creating {
+											return layout.Dimensions{}
+										}
+										gtx.Constraints.Min.X = gtx.Dp(unit.Dp(240))
+										gtx.Constraints.Max.X = gtx.Constraints.Min.X
+										border := widget.Border{
+											Color:        th.Fg,
+											CornerRadius: unit.Dp(8),
+											Width:        unit.Dp(1),
+										}
+										return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+											return border.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+												ed := material.Editor(th, &confirmEditor, "Repeat passphrase")
+												ed.TextSize = unit.Sp(14)
+												return layout.UniformInset(unit.Dp(8)).Layout(gtx, ed.Layout)
+											})
+										})
+									}),
+									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+										btn := material.Button(th, &unlockButton, label)
+										return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, btn.Layout)
+									}),
+									layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+										lbl := material.Body2(th, info)
+										lbl.Color = color.NRGBA{R: 150, G: 150, B: 150, A: 255}
+										return layout.Inset{Left: unit.Dp(12)}.Layout(gtx, lbl.Layout)
+									}),
+								)
+							})
+						}
+
+						if len(sessions) > 0 && prevSessionButton.Clicked(gtx) {
+							if sessionIndex <= 0 {
+								selectSession(len(sessions) - 1)
+							} else {
+								selectSession(sessionIndex - 1)
+							}
+						}
+						if len(sessions) > 0 && nextSessionButton.Clicked(gtx) {
+							selectSession((sessionIndex + 1) % len(sessions))
+						}
+						if deleteSessionButton.Clicked(gtx) && sessionIndex >= 0 {
+							name := sessions[sessionIndex].Name
+							if err := vault.DeleteSession(name); err != nil {
+								sessionStatus = "Failed to delete session: " + err.Error()
+								log.Println("Failed to delete session:", err)
+							}
+							sessionNameEditor.SetText("")
+							currentMapping = nil
+							refreshSessions("")
+							log.Println("Deleted session", name)
+						}
+
+						info := "No saved sessions"
+						if sessionIndex >= 0 {
+							sess := sessions[sessionIndex]
+							info = fmt.Sprintf("%s · %d/%d · %d values · %s", sess.Name, sessionIndex+1, len(sessions), len(sess.Mapping), sess.Updated.Format("2006-01-02 15:04"))
+						} else if len(sessions) > 0 {
+							info = fmt.Sprintf("%d saved sessions", len(sessions))
+						}
+						if sessionStatus != "" {
+							info = sessionStatus
+						}
+
+						return layout.Inset{Top: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return layout.Inset{Right: unit.Dp(8)}.Layout(gtx, material.Body1(th, "Session").Layout)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									gtx.Constraints.Min.X = gtx.Dp(unit.Dp(240))
+									gtx.Constraints.Max.X = gtx.Constraints.Min.X
+									border := widget.Border{
+										Color:        th.Fg,
+										CornerRadius: unit.Dp(8),
+										Width:        unit.Dp(1),
+									}
+									return border.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+										ed := material.Editor(th, &sessionNameEditor, "New session")
+										ed.TextSize = unit.Sp(14)
+										return layout.UniformInset(unit.Dp(8)).Layout(gtx, ed.Layout)
+									})
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									btn := material.Button(th, &prevSessionButton, "◀")
+									return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, btn.Layout)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									btn := material.Button(th, &nextSessionButton, "▶")
+									return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, btn.Layout)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									btn := material.Button(th, &deleteSessionButton, "Delete")
+									btn.Background = color.NRGBA{R: 0xFF, G: 0x88, B: 0x88, A: 0xFF}
+									return layout.Inset{Left: unit.Dp(8)}.Layout(gtx, btn.Layout)
+								}),
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									lbl := material.Body2(th, info)
+									lbl.Color = color.NRGBA{R: 150, G: 150, B: 150, A: 255}
+									return layout.Inset{Left: unit.Dp(12)}.Layout(gtx, lbl.Layout)
+								}),
+							)
+						})
+					}),
 					// Footer
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 						return layout.Inset{Top: unit.Dp(10)}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
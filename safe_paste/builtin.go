@@ -0,0 +1,138 @@
+package safe_paste
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// Built-in detector names, usable as keys in Config.Detectors
+const (
+	DetectorIPv4     = "ipv4"
+	DetectorIPv6     = "ipv6"
+	DetectorHostname = "hostname"
+	DetectorKeyword  = "keyword"
+)
+
+func init() {
+	// IPv4 first to avoid conflicts, keywords last to catch remaining sensitive words
+	RegisterDetector(DetectorSpec{Name: DetectorIPv4, Priority: 400, New: newIPv4Detector})
+	RegisterDetector(DetectorSpec{Name: DetectorIPv6, Priority: 300, New: newIPv6Detector})
+	RegisterDetector(DetectorSpec{Name: DetectorHostname, Priority: 200, New: newHostnameDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorKeyword, Priority: 100, New: newKeywordDetector})
+}
+
+// ipPseudonym returns the pseudonym function of an IP detector: documentation
+// addresses, or prefix-preserving ones in ModePrefixPreserving.
+func ipPseudonym(cfg Config, name string, documentation func(ip string, n int) string) (func(string, int) string, error) {
+	if detectorMode(cfg, name) != ModePrefixPreserving {
+		return documentation, nil
+	}
+	anon, err := newIPAnonymizer(cfg.IPAnonymizationKey)
+	if err != nil {
+		return nil, err
+	}
+	return func(ip string, n int) string {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return ""
+		}
+		fake, ok := anon.Anonymize(addr)
+		if !ok {
+			return ""
+		}
+		return fake.String()
+	}, nil
+}
+
+func newIPv4Detector(cfg Config) (Detector, error) {
+	preserved, err := preservedIPClasses(cfg)
+	if err != nil {
+		return nil, err
+	}
+	pseudonym, err := ipPseudonym(cfg, DetectorIPv4, func(ip string, n int) string { return pseudonymIPv4(n) })
+	if err != nil {
+		return nil, err
+	}
+	return &ipDetector{
+		name:    DetectorIPv4,
+		re:      ipv4Regex,
+		unit:    ipv4UnitRegex,
+		maxBits: 32,
+		validAddr: func(ip string) bool {
+			// Skip invalid IPs (e.g., 256.256.256.256) and preserved classes such as loopback
+			if !isValidIPv4(ip) {
+				return false
+			}
+			addr, err := netip.ParseAddr(ip)
+			return err != nil || !preserved[ipClass(addr)]
+		},
+		pseudonym: pseudonym,
+	}, nil
+}
+
+func newIPv6Detector(cfg Config) (Detector, error) {
+	preserved, err := preservedIPClasses(cfg)
+	if err != nil {
+		return nil, err
+	}
+	pseudonym, err := ipPseudonym(cfg, DetectorIPv6, func(ip string, n int) string {
+		// IPv4-mapped addresses stay IPv4-mapped
+		if addr, err := netip.ParseAddr(ip); err == nil && addr.Is4In6() {
+			if fake := pseudonymIPv4(n); fake != "" {
+				return "::ffff:" + fake
+			}
+			return ""
+		}
+		return pseudonymIPv6(n)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ipDetector{
+		name:    DetectorIPv6,
+		v6:      true,
+		re:      ipv6Regex,
+		unit:    ipv6UnitRegex,
+		maxBits: 128,
+		validAddr: func(ip string) bool {
+			addr, err := netip.ParseAddr(ip)
+			return err == nil && addr.Is6() && !preserved[ipClass(addr)]
+		},
+		pseudonym: pseudonym,
+	}, nil
+}
+
+// keywordDetector matches the configured keywords literally (case-sensitive)
+type keywordDetector struct {
+	keywords []string
+}
+
+func newKeywordDetector(cfg Config) (Detector, error) {
+	var keywords []string
+	for _, kw := range cfg.Keywords {
+		if kw != "" {
+			keywords = append(keywords, kw)
+		}
+	}
+	return &keywordDetector{keywords: keywords}, nil
+}
+
+func (d *keywordDetector) Name() string            { return DetectorKeyword }
+func (d *keywordDetector) TokenPrefix() string     { return "kw" }
+func (d *keywordDetector) Validate(kw string) bool { return true }
+
+func (d *keywordDetector) FindMatches(text string) [][]int {
+	var matches [][]int
+	for _, kw := range d.keywords {
+		for offset := 0; ; {
+			i := strings.Index(text[offset:], kw)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			matches = append(matches, []int{start, start + len(kw)})
+			offset = start + len(kw)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,195 @@
+package safe_paste
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Cloud identifier detector names
+const (
+	DetectorARN        = "arn"
+	DetectorAWSAccount = "aws_account"
+	DetectorAzureID    = "azure_resource"
+	DetectorGCPProject = "gcp_project"
+	DetectorUUID       = "uuid"
+)
+
+var (
+	// arn:partition:service:region:account:resource
+	arnRegex = regexp.MustCompile(`\barn:aws(?:-cn|-us-gov)?:[a-z0-9-]+:[a-z0-9-]*:(?:[0-9]{12})?:[\w/:.*+=@-]*[\w*]`)
+	// 12-digit account IDs only count after a label, e.g. "AccountId": "123456789012"
+	awsAccountRegex = regexp.MustCompile(`(?i:account[_ -]?id|aws[_-]?account(?:[_-]?id)?|account)["']?\s*[:=]\s*["']?(?P<value>[0-9]{12})\b`)
+	// /subscriptions/{id}/resourceGroups/{name}/providers/{namespace}/{type}/{name}...
+	azureIDRegex = regexp.MustCompile(`(?i)/subscriptions/[0-9a-f]{8}-(?:[0-9a-f]{4}-){3}[0-9a-f]{12}(?:/[\w.-]+)*`)
+	// projects/{id}/{collection}/{name}..., or a project ID after a label
+	gcpProjectRegex = regexp.MustCompile(`\b(?P<value>projects/[a-z][a-z0-9-]{4,28}[a-z0-9](?:/[\w.@-]+)*)\b|(?i:project[_-]?id|gcp[_-]?project)["']?\s*[:=]\s*["']?(?P<value>[a-z][a-z0-9-]{4,28}[a-z0-9])\b`)
+	uuidRegex       = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+)
+
+// gcpLocations are GCP collections whose names are public locations, not tenancy
+var gcpLocations = map[string]bool{"zones": true, "regions": true, "locations": true}
+
+func init() {
+	// Above the entropy detector, which would take resource paths for secrets
+	RegisterDetector(DetectorSpec{Name: DetectorARN, Priority: 480, New: newARNDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorAzureID, Priority: 475, New: newAzureIDDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorGCPProject, Priority: 470, New: newGCPProjectDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorAWSAccount, Priority: 465, New: newAWSAccountDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorUUID, Priority: 330, New: newUUIDDetector})
+}
+
+// cloudPart returns a part detector. Parts share tokens by prefix, so an
+// account gets the same token inside an ARN and after a label.
+func cloudPart(name, prefix string, pseudonym func(string, int) string) Detector {
+	return &regexDetector{name: name, prefix: prefix, pseudonym: pseudonym}
+}
+
+// arnDetector masks the account and the resource of ARNs separately, keeping
+// the partition, service, region and resource type
+type arnDetector struct {
+	regexDetector
+	account, resource Detector
+}
+
+func newARNDetector(cfg Config) (Detector, error) {
+	return &arnDetector{
+		regexDetector: regexDetector{name: DetectorARN, prefix: "arn", re: arnRegex},
+		account:       cloudPart(DetectorARN, "account", pseudonymName),
+		resource:      cloudPart(DetectorARN, "resource", pseudonymName),
+	}, nil
+}
+
+// Split turns arn:aws:iam::123456789012:role/Admin into arn:aws:iam::account1:role/resource1
+func (d *arnDetector) Split(arn string) []Part {
+	fields := strings.SplitN(arn, ":", 6)
+	if len(fields) != 6 {
+		return nil
+	}
+	head := strings.Join(fields[:4], ":") + ":"
+	parts := []Part{{Text: head}}
+	if account := fields[4]; account != "" {
+		parts = append(parts, Part{Text: account, Detector: d.account})
+	}
+	parts = append(parts, Part{Text: ":"})
+
+	// The resource type (role/, function:) is kept; S3 resources are bucket names
+	resource := fields[5]
+	if i := strings.IndexAny(resource, "/:"); i >= 0 && fields[2] != "s3" {
+		parts = append(parts, Part{Text: resource[:i+1]})
+		resource = resource[i+1:]
+	}
+	if resource != "" && resource != "*" {
+		parts = append(parts, Part{Text: resource, Detector: d.resource})
+	} else {
+		parts = append(parts, Part{Text: resource})
+	}
+	return parts
+}
+
+func newAWSAccountDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorAWSAccount, prefix: "account", re: awsAccountRegex, pseudonym: pseudonymName}, nil
+}
+
+// azureIDDetector masks the subscription, resource group and resource names of
+// Azure resource IDs, keeping the provider namespaces and resource types
+type azureIDDetector struct {
+	regexDetector
+	subscription, group, resource Detector
+}
+
+func newAzureIDDetector(cfg Config) (Detector, error) {
+	return &azureIDDetector{
+		regexDetector: regexDetector{name: DetectorAzureID, prefix: "azure", re: azureIDRegex},
+		subscription:  cloudPart(DetectorAzureID, "subscription", pseudonymHex),
+		group:         cloudPart(DetectorAzureID, "rg", pseudonymName),
+		resource:      cloudPart(DetectorAzureID, "resource", pseudonymName),
+	}, nil
+}
+
+// Split turns /subscriptions/{id}/resourceGroups/{rg}/providers/Microsoft.Compute/virtualMachines/{vm}
+// into /subscriptions/subscription1/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachines/resource1
+func (d *azureIDDetector) Split(id string) []Part {
+	segments := strings.Split(id[1:], "/")
+	parts := []Part{{Text: "/"}}
+	detectorFor := func(key string) Detector {
+		switch strings.ToLower(key) {
+		case "subscriptions":
+			return d.subscription
+		case "resourcegroups":
+			return d.group
+		}
+		return d.resource
+	}
+	for i := 0; i < len(segments); i++ {
+		if i > 0 {
+			parts = append(parts, Part{Text: "/"})
+		}
+		key := segments[i]
+		parts = append(parts, Part{Text: key})
+		if strings.EqualFold(key, "providers") && i+1 < len(segments) {
+			// The namespace (Microsoft.Compute) is followed by type/name pairs
+			i++
+			parts = append(parts, Part{Text: "/"}, Part{Text: segments[i]})
+			continue
+		}
+		if i+1 < len(segments) {
+			i++
+			parts = append(parts, Part{Text: "/"}, Part{Text: segments[i], Detector: detectorFor(key)})
+		}
+	}
+	return parts
+}
+
+// gcpProjectDetector masks project IDs and the resource names in GCP resource
+// paths, keeping collections and locations. Labeled project IDs are masked whole.
+type gcpProjectDetector struct {
+	regexDetector
+	project, resource Detector
+}
+
+func newGCPProjectDetector(cfg Config) (Detector, error) {
+	return &gcpProjectDetector{
+		regexDetector: regexDetector{name: DetectorGCPProject, prefix: "project", re: gcpProjectRegex, pseudonym: pseudonymName},
+		project:       cloudPart(DetectorGCPProject, "project", pseudonymName),
+		resource:      cloudPart(DetectorGCPProject, "resource", pseudonymName),
+	}, nil
+}
+
+// Split turns projects/my-proj/zones/us-central1-a/instances/vm-1
+// into projects/project1/zones/us-central1-a/instances/resource1
+func (d *gcpProjectDetector) Split(path string) []Part {
+	if !strings.HasPrefix(path, "projects/") {
+		return nil
+	}
+	segments := strings.Split(path, "/")
+	var parts []Part
+	for i := 0; i < len(segments); i += 2 {
+		if i > 0 {
+			parts = append(parts, Part{Text: "/"})
+		}
+		collection := segments[i]
+		parts = append(parts, Part{Text: collection})
+		if i+1 >= len(segments) {
+			break
+		}
+		parts = append(parts, Part{Text: "/"})
+		switch {
+		case i == 0:
+			parts = append(parts, Part{Text: segments[1], Detector: d.project})
+		case gcpLocations[collection]:
+			parts = append(parts, Part{Text: segments[i+1]})
+		default:
+			parts = append(parts, Part{Text: segments[i+1], Detector: d.resource})
+		}
+	}
+	return parts
+}
+
+func newUUIDDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorUUID, prefix: "uuid", re: uuidRegex, validate: isUUID, pseudonym: pseudonymHex}, nil
+}
+
+// isUUID skips the nil UUID, which identifies nothing
+func isUUID(s string) bool {
+	return s != "00000000-0000-0000-0000-000000000000"
+}
@@ -0,0 +1,67 @@
+package safe_paste
+
+import "testing"
+
+func TestMaskCloudIdentifiers(t *testing.T) {
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{
+			"User: arn:aws:iam::123456789012:role/Admin is not authorized, AccountId: 123456789012",
+			"User: arn:aws:iam::account1:role/resource1 is not authorized, AccountId: account1",
+		},
+		{
+			"arn:aws:lambda:eu-west-1:210987654321:function:billing-sync and arn:aws:s3:::acme-logs/2024/",
+			"arn:aws:lambda:eu-west-1:account1:function:resource1 and arn:aws:s3:::resource2/",
+		},
+		{
+			"policy arn:aws:iam::aws:policy/ReadOnlyAccess on arn:aws:s3:::*",
+			"policy arn:aws:iam::aws:policy/ReadOnlyAccess on arn:aws:s3:::*",
+		},
+		{
+			"id /subscriptions/0b1f6471-1bf0-4dda-aec3-cb9272f09590/resourceGroups/prod-rg/providers/Microsoft.Compute/virtualMachines/web-01 failed",
+			"id /subscriptions/subscription1/resourceGroups/rg1/providers/Microsoft.Compute/virtualMachines/resource1 failed",
+		},
+		{
+			"GET https://management.azure.com/subscriptions/0b1f6471-1bf0-4dda-aec3-cb9272f09590/resourceGroups/prod-rg?api-version=2021-04-01",
+			"GET https://management.azure.com/subscriptions/subscription1/resourceGroups/rg1?api-version=2021-04-01",
+		},
+		{
+			"projects/acme-prod-42/zones/us-central1-a/instances/vm-1 in project_id: acme-prod-42",
+			"projects/project1/zones/us-central1-a/instances/resource1 in project_id: project1",
+		},
+		{
+			"request 7c9e6679-7425-40de-944b-e07fc1f90ae7 trace 00000000-0000-0000-0000-000000000000",
+			"request uuid1 trace 00000000-0000-0000-0000-000000000000",
+		},
+		{"account 123456789012 alone", "account 123456789012 alone"},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, Config{}, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) =\n%q, want\n%q", tt.input, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != tt.input {
+			t.Errorf("round trip of %q = %q", tt.input, got)
+		}
+	}
+}
+
+func TestGCPProjectPathBoundary(t *testing.T) {
+	// "billing_exports" is no project ID: the ID pattern stops at "billing"
+	input := "copy projects/acme-prod-42/topics/orders to projects/billing_exports/2024-05.csv"
+	result := maskWithConfig(t, Config{}, input)
+	want := []Span{
+		{Start: 14, End: 26, MaskedStart: 14, MaskedEnd: 22, Detector: DetectorGCPProject, Original: "acme-prod-42", Token: "project1"},
+		{Start: 34, End: 40, MaskedStart: 30, MaskedEnd: 39, Detector: DetectorGCPProject, Original: "orders", Token: "resource1"},
+	}
+	if len(result.Spans) != len(want) {
+		t.Fatalf("Spans = %+v, want %+v", result.Spans, want)
+	}
+	for i, span := range result.Spans {
+		if span != want[i] {
+			t.Errorf("Spans[%d] = %+v, want %+v", i, span, want[i])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package safe_paste
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"net/netip"
+	"sync"
+)
+
+// ipAnonymizer is a prefix-preserving IP anonymizer in the style of Crypto-PAn:
+// two addresses sharing an n-bit prefix are mapped to addresses sharing an
+// n-bit prefix. It is safe for concurrent use.
+type ipAnonymizer struct {
+	block cipher.Block
+	pad   [16]byte
+}
+
+// specialIPv4 and specialIPv6 are ranges whose prefix is kept as is, so private,
+// link-local, multicast... addresses stay in their class after anonymization.
+var (
+	specialIPv4 = mustPrefixes(
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+		"172.16.0.0/12", "192.0.2.0/24", "192.168.0.0/16", "198.18.0.0/15",
+		"198.51.100.0/24", "203.0.113.0/24", "224.0.0.0/4", "240.0.0.0/4",
+	)
+	specialIPv6 = mustPrefixes(
+		"::/96", "::ffff:0:0/96", "64:ff9b::/96", "2001:db8::/32",
+		"fc00::/7", "fe80::/10", "ff00::/8",
+	)
+)
+
+func mustPrefixes(prefixes ...string) []netip.Prefix {
+	parsed := make([]netip.Prefix, len(prefixes))
+	for i, p := range prefixes {
+		parsed[i] = netip.MustParsePrefix(p)
+	}
+	return parsed
+}
+
+// randomIPSecret is used when no key is configured. It is picked once per run,
+// so the IP detectors and the URL detector anonymize alike.
+var randomIPSecret = sync.OnceValues(func() ([32]byte, error) {
+	var secret [32]byte
+	_, err := rand.Read(secret[:])
+	return secret, err
+})
+
+// newIPAnonymizer derives the AES key and pad from key. An empty key uses a
+// random one, so addresses are only consistent within one run.
+func newIPAnonymizer(key string) (*ipAnonymizer, error) {
+	var secret [32]byte
+	if key == "" {
+		var err error
+		if secret, err = randomIPSecret(); err != nil {
+			return nil, err
+		}
+	} else {
+		secret = sha256.Sum256([]byte(key))
+	}
+	block, err := aes.NewCipher(secret[:16])
+	if err != nil {
+		return nil, err
+	}
+	a := &ipAnonymizer{block: block}
+	block.Encrypt(a.pad[:], secret[16:])
+	return a, nil
+}
+
+// maxCycleWalk bounds the re-encryptions used to keep an address in its class
+const maxCycleWalk = 64
+
+// Anonymize maps addr to another address of the same family and class.
+// ok is false if no suitable address was found.
+func (a *ipAnonymizer) Anonymize(addr netip.Addr) (netip.Addr, bool) {
+	// IPv4-mapped addresses are anonymized as IPv4, so they match the plain form
+	if addr.Is4In6() {
+		fake, ok := a.Anonymize(addr.Unmap())
+		if !ok {
+			return netip.Addr{}, false
+		}
+		return netip.AddrFrom16(fake.As16()), true
+	}
+	// The class only depends on the network part: no special range is longer
+	specials, netBits := specialIPv6, 96
+	if addr.Is4() {
+		specials, netBits = specialIPv4, 24
+	}
+	keep := 0 // prefix bits kept as is
+	for _, p := range specials {
+		if p.Contains(addr) {
+			keep = p.Bits()
+			break
+		}
+	}
+
+	in := addr.AsSlice()
+	bits := len(in) * 8
+	// Keep network (.0) and broadcast (.255) addresses recognizable
+	edge := addr.Is4() && (in[3] == 0 || in[3] == 255)
+	if edge {
+		bits = 24
+	}
+	out := a.permute(in, 0, bits)
+	copyBits(out, in, keep)
+
+	// Cycle walking: a public address landing in a special range is encrypted
+	// again. Only the network part is walked, so hosts of one network walk together.
+	for try := 0; keep == 0 && inAny(specials, addrOf(out)); try++ {
+		if try == maxCycleWalk {
+			return netip.Addr{}, false
+		}
+		out = a.permute(out, 0, netBits)
+	}
+	// A host must not look like a network or broadcast address. Only the last
+	// octet is walked, keyed by the original network, so the host keeps its /24.
+	for try := 0; addr.Is4() && !edge && (out[3] == 0 || out[3] == 255); try++ {
+		if try == maxCycleWalk {
+			return netip.Addr{}, false
+		}
+		out[3] = a.permute([]byte{in[0], in[1], in[2], out[3]}, 24, 32)[3]
+	}
+	return addrOf(out), true
+}
+
+func addrOf(b []byte) netip.Addr {
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// permute applies the Crypto-PAn one-time pad to bits from..to of addr.
+// Output bit i only depends on input bits 0..i, which preserves prefixes.
+func (a *ipAnonymizer) permute(addr []byte, from, to int) []byte {
+	out := make([]byte, len(addr))
+	copy(out, addr)
+	var block, enc [16]byte
+	for i := from; i < to; i++ {
+		block = a.pad
+		copyBits(block[:], addr, i)
+		a.block.Encrypt(enc[:], block[:])
+		if enc[0]&0x80 != 0 {
+			out[i/8] ^= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// copyBits copies the first n bits of src into dst
+func copyBits(dst, src []byte, n int) {
+	copy(dst[:n/8], src[:n/8])
+	if rem := n % 8; rem != 0 {
+		mask := byte(0xff << (8 - rem))
+		dst[n/8] = dst[n/8]&^mask | src[n/8]&mask
+	}
+}
+
+func inAny(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
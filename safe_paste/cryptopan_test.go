@@ -0,0 +1,156 @@
+package safe_paste
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestIPAnonymizerPreservesPrefixes(t *testing.T) {
+	anon, err := newIPAnonymizer("test key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anonymize := func(s string) netip.Addr {
+		fake, ok := anon.Anonymize(netip.MustParseAddr(s))
+		if !ok {
+			t.Fatalf("Anonymize(%s) failed", s)
+		}
+		return fake
+	}
+	commonBits := func(a, b netip.Addr) int {
+		x, y := a.AsSlice(), b.AsSlice()
+		for i := 0; i < len(x)*8; i++ {
+			if (x[i/8]^y[i/8])&(0x80>>(i%8)) != 0 {
+				return i
+			}
+		}
+		return len(x) * 8
+	}
+
+	pairs := [][2]string{
+		{"10.1.2.3", "10.1.2.4"},
+		{"10.1.2.3", "10.9.0.1"},
+		{"8.8.8.8", "8.8.4.4"},
+		{"2001:4860::8888", "2001:4860::8844"},
+	}
+	for _, p := range pairs {
+		a, b := netip.MustParseAddr(p[0]), netip.MustParseAddr(p[1])
+		fa, fb := anonymize(p[0]), anonymize(p[1])
+		if got, want := commonBits(fa, fb), commonBits(a, b); got != want {
+			t.Errorf("%s, %s -> %s, %s share %d bits, want %d", a, b, fa, fb, got, want)
+		}
+	}
+
+	classes := []struct {
+		ip     string
+		prefix string
+	}{
+		{"10.1.2.3", "10.0.0.0/8"},
+		{"172.16.5.4", "172.16.0.0/12"},
+		{"192.168.1.20", "192.168.0.0/16"},
+		{"fd12:3456::1", "fc00::/7"},
+		{"fe80::1", "fe80::/10"},
+	}
+	for _, c := range classes {
+		if fake := anonymize(c.ip); !netip.MustParsePrefix(c.prefix).Contains(fake) || fake.String() == c.ip {
+			t.Errorf("Anonymize(%s) = %s, want another address in %s", c.ip, fake, c.prefix)
+		}
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2001:4860::8888"} {
+		fake := anonymize(ip)
+		if inAny(specialIPv4, fake) || inAny(specialIPv6, fake) {
+			t.Errorf("Anonymize(%s) = %s, want a public address", ip, fake)
+		}
+	}
+	for _, ip := range []string{"10.1.2.0", "10.1.2.255", "8.8.8.0"} {
+		fake := anonymize(ip)
+		if !strings.HasSuffix(fake.String(), ip[strings.LastIndex(ip, "."):]) {
+			t.Errorf("Anonymize(%s) = %s, want the same last octet", ip, fake)
+		}
+	}
+	if fake := anonymize("10.1.2.3"); fake != anonymize("10.1.2.3") {
+		t.Error("Anonymize is not deterministic")
+	}
+}
+
+func TestIPAnonymizerKeepsSubnets(t *testing.T) {
+	networks := []string{"1.1.1", "10.1.0", "10.1.2", "172.16.5", "192.168.1"}
+	for i := 0; i < 50; i++ {
+		networks = append(networks, fmt.Sprintf("8.8.%d", i))
+	}
+	for _, key := range []string{"k", "test key"} {
+		anon, err := newIPAnonymizer(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, network := range networks {
+			var subnet netip.Prefix
+			seen := make(map[netip.Addr]bool)
+			for host := 0; host < 256; host++ {
+				ip := netip.MustParseAddr(fmt.Sprintf("%s.%d", network, host))
+				fake, ok := anon.Anonymize(ip)
+				if !ok {
+					t.Fatalf("key %q: Anonymize(%s) failed", key, ip)
+				}
+				if host == 0 {
+					subnet = netip.PrefixFrom(fake, 24).Masked()
+				}
+				if !subnet.Contains(fake) {
+					t.Errorf("key %q: %s -> %s, outside %s where %s.0 went", key, ip, fake, subnet, network)
+				}
+				if seen[fake] {
+					t.Errorf("key %q: %s -> %s, already used", key, ip, fake)
+				}
+				seen[fake] = true
+				if last := fake.As4()[3]; (host == 0 || host == 255) != (last == 0 || last == 255) {
+					t.Errorf("key %q: %s -> %s, want the same kind of last octet", key, ip, fake)
+				}
+			}
+		}
+	}
+}
+
+func TestMaskPrefixPreservingMode(t *testing.T) {
+	m, err := NewMasker(Config{
+		HostnamePattern:    `\bxy-[a-z0-9.-]+\b`,
+		MaskingMode:        ModePrefixPreserving,
+		IPAnonymizationKey: "test key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "route 10.1.2.3 via 10.1.2.1 on xy-gw, dns 8.8.8.8"
+	result := m.Mask(input)
+	for _, span := range result.Spans {
+		if span.Detector == DetectorHostname && span.Token != "hostname1" {
+			t.Errorf("hostname masked as %q, want a token", span.Token)
+		}
+		if span.Detector == DetectorIPv4 {
+			if _, err := netip.ParseAddr(span.Token); err != nil || span.Token == span.Original {
+				t.Errorf("%s masked as %q, want another address", span.Original, span.Token)
+			}
+		}
+	}
+	if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+		t.Errorf("round trip = %q, want %q", got, input)
+	}
+
+	again, _ := NewMasker(Config{MaskingMode: ModePrefixPreserving, IPAnonymizationKey: "test key"})
+	if got := again.Mask("10.1.2.3").MaskedText; !strings.Contains(result.MaskedText, got) {
+		t.Errorf("same key gave %q, want a value from %q", got, result.MaskedText)
+	}
+}
+
+func TestPrefixPreservingModeUnsupported(t *testing.T) {
+	_, err := NewMasker(Config{
+		HostnamePattern: `\bxy-[a-z0-9.-]+\b`,
+		Detectors:       map[string]DetectorConfig{DetectorHostname: {Mode: ModePrefixPreserving}},
+	})
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Detector != DetectorHostname || !errors.Is(err, errUnsupportedMode) {
+		t.Errorf("NewMasker() error = %v, want an unsupported mode error for %q", err, DetectorHostname)
+	}
+}
@@ -0,0 +1,221 @@
+package safe_paste
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Detector finds one kind of sensitive value (IPs, hostnames, keywords...) in text.
+type Detector interface {
+	// Name identifies the detector in Config.Detectors
+	Name() string
+	// TokenPrefix is used to build tokens, e.g. "ip" -> ip1, ip2
+	TokenPrefix() string
+	// FindMatches returns candidate [start, end) byte offsets in text
+	FindMatches(text string) [][]int
+	// Validate reports whether a candidate really needs masking
+	Validate(candidate string) bool
+}
+
+// Splitter is implemented by detectors whose matches are masked piece by piece,
+// e.g. an email as user1@hostname1. Each piece gets its own mapping entry.
+type Splitter interface {
+	// Split returns the parts of a match in order, or nil to mask it as a whole
+	Split(match string) []Part
+}
+
+// Part is a piece of a split match. Parts with a Detector are masked with its
+// token prefix and mode; the others are kept as they are. Open parts may still
+// be masked by other detectors, e.g. a keyword in the path of a URL.
+type Part struct {
+	Text     string
+	Detector Detector
+	Open     bool
+}
+
+// DetectorFactory builds a detector from the current config
+type DetectorFactory func(cfg Config) (Detector, error)
+
+// DetectorSpec describes a registered detector and its defaults
+type DetectorSpec struct {
+	Name     string
+	Priority int  // higher priority detectors run first
+	Disabled bool // disabled unless enabled in Config.Detectors
+	New      DetectorFactory
+}
+
+// DetectorConfig overrides the defaults of a registered detector
+type DetectorConfig struct {
+	Enabled  *bool  `json:"enabled,omitempty"`
+	Priority *int   `json:"priority,omitempty"`
+	Mode     string `json:"mode,omitempty"` // overrides Config.MaskingMode
+}
+
+// ConfigError reports an invalid rule found while building a Masker
+type ConfigError struct {
+	Detector string // detector name, empty for global settings
+	Field    string // config field, e.g. "hostname_pattern"
+	Value    string // offending value
+	Err      error
+}
+
+func (e *ConfigError) Error() string {
+	msg := e.Field
+	if e.Detector != "" {
+		msg = e.Detector + ": " + msg
+	}
+	if e.Value != "" {
+		msg += fmt.Sprintf(" %q", e.Value)
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ErrUnknownDetector is returned when Config.Detectors names an unregistered detector
+var ErrUnknownDetector = errors.New("unknown detector")
+
+var (
+	registryMu sync.RWMutex
+	registry   []DetectorSpec
+)
+
+// RegisterDetector makes a detector available to the masking engine.
+// It panics if the name is empty or already registered, like database/sql.Register.
+func RegisterDetector(spec DetectorSpec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if spec.Name == "" || spec.New == nil {
+		panic("safe_paste: RegisterDetector needs a name and a factory")
+	}
+	for _, s := range registry {
+		if s.Name == spec.Name {
+			panic("safe_paste: detector registered twice: " + spec.Name)
+		}
+	}
+	registry = append(registry, spec)
+}
+
+// RegisteredDetectors returns the names of all registered detectors
+func RegisteredDetectors() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for _, s := range registry {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// buildDetectors returns the enabled detectors ordered by priority (highest first).
+// Ties keep registration order.
+func buildDetectors(cfg Config) ([]Detector, error) {
+	registryMu.RLock()
+	specs := make([]DetectorSpec, len(registry))
+	copy(specs, registry)
+	registryMu.RUnlock()
+
+	known := make(map[string]bool, len(specs))
+	for _, s := range specs {
+		known[s.Name] = true
+	}
+	for name := range cfg.Detectors {
+		if !known[name] {
+			return nil, &ConfigError{Field: "detectors", Value: name, Err: ErrUnknownDetector}
+		}
+	}
+
+	profiles, err := profileDetectors(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		priority int
+		detector Detector
+	}
+	var enabled []entry
+	for _, s := range specs {
+		on, priority := !s.Disabled || profiles[s.Name], s.Priority
+		if dc, ok := cfg.Detectors[s.Name]; ok {
+			if dc.Enabled != nil {
+				on = *dc.Enabled
+			}
+			if dc.Priority != nil {
+				priority = *dc.Priority
+			}
+		}
+		if !on {
+			continue
+		}
+		d, err := s.New(cfg)
+		if err != nil {
+			var ce *ConfigError
+			if !errors.As(err, &ce) {
+				ce = &ConfigError{Err: err}
+			}
+			if ce.Detector == "" {
+				ce.Detector = s.Name
+			}
+			return nil, ce
+		}
+		enabled = append(enabled, entry{priority, d})
+	}
+	sort.SliceStable(enabled, func(i, j int) bool {
+		return enabled[i].priority > enabled[j].priority
+	})
+
+	detectors := make([]Detector, len(enabled))
+	for i, e := range enabled {
+		detectors[i] = e.detector
+	}
+	return detectors, nil
+}
+
+// regexDetector is a Detector backed by a single regular expression.
+// If the expression has groups named "value", only the first one that
+// matched is masked, e.g. the password of "password=hunter2".
+type regexDetector struct {
+	name      string
+	prefix    string
+	re        *regexp.Regexp
+	validate  func(string) bool
+	pseudonym func(original string, n int) string // nil: tokens only
+}
+
+func (d *regexDetector) Name() string        { return d.name }
+func (d *regexDetector) TokenPrefix() string { return d.prefix }
+
+func (d *regexDetector) FindMatches(text string) [][]int {
+	if d.re.SubexpIndex("value") < 0 {
+		return d.re.FindAllStringIndex(text, -1)
+	}
+	names := d.re.SubexpNames()
+	var matches [][]int
+	for _, m := range d.re.FindAllStringSubmatchIndex(text, -1) {
+		for i, name := range names {
+			if name == "value" && m[2*i] >= 0 {
+				matches = append(matches, m[2*i:2*i+2])
+				break
+			}
+		}
+	}
+	return matches
+}
+
+func (d *regexDetector) Validate(candidate string) bool {
+	if d.validate == nil {
+		return true
+	}
+	return d.validate(candidate)
+}
+
+func (d *regexDetector) Pseudonym(original string, n int) string {
+	if d.pseudonym == nil {
+		return ""
+	}
+	return d.pseudonym(original, n)
+}
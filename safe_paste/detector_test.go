@@ -0,0 +1,98 @@
+package safe_paste
+
+import (
+	"regexp"
+	"testing"
+)
+
+func init() {
+	// Custom detector used by the tests below, disabled unless a config enables it
+	RegisterDetector(DetectorSpec{
+		Name:     "test_ticket",
+		Priority: 50,
+		Disabled: true,
+		New: func(cfg Config) (Detector, error) {
+			return &regexDetector{name: "test_ticket", prefix: "ticket", re: regexp.MustCompile(`\bJIRA-[0-9]+\b`)}, nil
+		},
+	})
+}
+
+func detectorNames(detectors []Detector) []string {
+	names := make([]string, len(detectors))
+	for i, d := range detectors {
+		names[i] = d.Name()
+	}
+	return names
+}
+
+func TestBuildDetectorsDefaultOrder(t *testing.T) {
+	detectors, err := buildDetectors(Config{HostnamePattern: `\bxy-[a-z0-9.-]+\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := detectorNames(detectors)
+	want := []string{
+		DetectorPrivateKey, DetectorJWT, DetectorAWSKey, DetectorGitHubToken, DetectorSlackToken,
+		DetectorBearerToken, DetectorURL, DetectorPassword, DetectorARN, DetectorAzureID, DetectorGCPProject,
+		DetectorAWSAccount, DetectorIPv4, DetectorUUID, DetectorWWN, DetectorMAC, DetectorIPv6, DetectorUsername,
+		DetectorSerial, DetectorHostname, DetectorKeyword,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("detectors = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("detectors = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBuildDetectorsConfigOverrides(t *testing.T) {
+	on, off, top := true, false, 1000
+	cfg := Config{
+		HostnamePattern: `\bxy-[a-z0-9.-]+\b`,
+		Detectors: map[string]DetectorConfig{
+			"test_ticket":   {Enabled: &on, Priority: &top},
+			DetectorIPv6:    {Enabled: &off},
+			DetectorKeyword: {Enabled: &off},
+		},
+	}
+	detectors, err := buildDetectors(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := detectorNames(detectors)
+	want := []string{
+		"test_ticket", DetectorPrivateKey, DetectorJWT, DetectorAWSKey, DetectorGitHubToken,
+		DetectorSlackToken, DetectorBearerToken, DetectorURL, DetectorPassword, DetectorARN, DetectorAzureID,
+		DetectorGCPProject, DetectorAWSAccount, DetectorIPv4, DetectorUUID, DetectorWWN, DetectorMAC, DetectorUsername,
+		DetectorSerial, DetectorHostname,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("detectors = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("detectors = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBuildDetectorsErrors(t *testing.T) {
+	if _, err := buildDetectors(Config{HostnamePattern: `(`}); err == nil {
+		t.Error("expected error for invalid hostname pattern")
+	}
+	cfg := Config{Detectors: map[string]DetectorConfig{"nope": {}}}
+	if _, err := buildDetectors(cfg); err == nil {
+		t.Error("expected error for unknown detector")
+	}
+}
+
+func TestRegisterDetectorDuplicatePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for duplicate registration")
+		}
+	}()
+	RegisterDetector(DetectorSpec{Name: DetectorIPv4, New: newIPv4Detector})
+}
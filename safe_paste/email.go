@@ -0,0 +1,72 @@
+package safe_paste
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Email modes for Config.EmailMode
+const (
+	EmailWhole    = "whole"    // alice@xy-mail.prod -> email1 (default)
+	EmailLocal    = "local"    // alice@xy-mail.prod -> user1@xy-mail.prod
+	EmailDomain   = "domain"   // alice@xy-mail.prod -> alice@domain1
+	EmailHostname = "hostname" // alice@xy-mail.prod -> user1@hostname1, sharing the hostname detector's tokens
+)
+
+var (
+	emailRegex = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)
+
+	errEmailMode = errors.New(`must be "whole", "local", "domain" or "hostname"`)
+)
+
+// emailDetector masks email addresses as a whole or part by part
+type emailDetector struct {
+	regexDetector
+	mode          string
+	local, domain Detector          // detectors of the masked parts, nil keeps a part
+	hosts         *hostnameDetector // splits the domain in EmailHostname mode
+}
+
+func newEmailDetector(cfg Config) (Detector, error) {
+	d := &emailDetector{
+		regexDetector: regexDetector{name: DetectorEmail, prefix: "email", re: emailRegex, pseudonym: pseudonymName},
+		mode:          cfg.EmailMode,
+	}
+	// The parts are masked in the email detector's mode, hostnames in the hostname detector's
+	user := &regexDetector{name: DetectorEmail, prefix: "user", pseudonym: pseudonymName}
+	switch cfg.EmailMode {
+	case "", EmailWhole:
+	case EmailLocal:
+		d.local = user
+	case EmailDomain:
+		d.domain = &regexDetector{name: DetectorEmail, prefix: "domain", pseudonym: pseudonymName}
+	case EmailHostname:
+		hosts, err := newHostnames(cfg)
+		if err != nil {
+			return nil, err
+		}
+		d.local, d.domain, d.hosts = user, hosts, hosts
+	default:
+		return nil, &ConfigError{Field: "email_mode", Value: cfg.EmailMode, Err: errEmailMode}
+	}
+	return d, nil
+}
+
+func (d *emailDetector) Split(email string) []Part {
+	if d.local == nil && d.domain == nil {
+		return nil
+	}
+	at := strings.LastIndexByte(email, '@')
+	parts := []Part{
+		{Text: email[:at], Detector: d.local},
+		{Text: "@"},
+	}
+	// The domain is split like a bare hostname, so both get the same token
+	if d.hosts != nil {
+		if split := d.hosts.Split(email[at+1:]); split != nil {
+			return append(parts, split...)
+		}
+	}
+	return append(parts, Part{Text: email[at+1:], Detector: d.domain})
+}
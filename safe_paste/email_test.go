@@ -0,0 +1,83 @@
+package safe_paste
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaskEmailModes(t *testing.T) {
+	input := "from alice@xy-mail.prod via xy-mail.prod, cc bob@example.com"
+	tests := []struct {
+		mode   string
+		masked string
+	}{
+		{"", "from email1 via hostname1, cc email2"},
+		{EmailWhole, "from email1 via hostname1, cc email2"},
+		{EmailLocal, "from user1@xy-mail.prod via hostname1, cc user2@example.com"},
+		{EmailDomain, "from alice@domain1 via hostname1, cc bob@domain2"},
+		{EmailHostname, "from user1@hostname1 via hostname1, cc user2@hostname2"},
+	}
+	for _, tt := range tests {
+		cfg := Config{
+			HostnamePattern: `\bxy-[a-z0-9.-]+\b`,
+			Profiles:        []string{ProfileContact},
+			EmailMode:       tt.mode,
+		}
+		result := maskWithConfig(t, cfg, input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("mode %q: Mask = %q, want %q", tt.mode, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+			t.Errorf("mode %q: round trip = %q", tt.mode, got)
+		}
+	}
+}
+
+func TestMaskEmailInternalDomains(t *testing.T) {
+	input := "mail alice@db01.eu.corp.example.com on db01.eu.corp.example.com"
+	tests := []struct {
+		mode   string
+		masked string
+	}{
+		{HostnameWhole, "mail user1@hostname1 on hostname1"},
+		{HostnameSubdomain, "mail user1@hostname1.corp.example.com on hostname1.corp.example.com"},
+		{HostnameLabels, "mail user1@label1.label2.corp.example.com on label1.label2.corp.example.com"},
+	}
+	for _, tt := range tests {
+		cfg := Config{
+			InternalDomains: []string{"corp.example.com"},
+			HostnameMode:    tt.mode,
+			Profiles:        []string{ProfileContact},
+			EmailMode:       EmailHostname,
+		}
+		result := maskWithConfig(t, cfg, input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("mode %q: Mask = %q, want %q", tt.mode, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+			t.Errorf("mode %q: round trip = %q", tt.mode, got)
+		}
+	}
+}
+
+func TestMaskEmailPseudonyms(t *testing.T) {
+	cfg := Config{Profiles: []string{ProfileContact}, EmailMode: EmailLocal, MaskingMode: ModePseudonym}
+	input := "mail alice@example.com"
+	result := maskWithConfig(t, cfg, input)
+	for fake, original := range result.Mapping {
+		if original != "alice" || len(fake) != len(original) || fake == "user1" {
+			t.Errorf("mapping %q -> %q, want a pseudonym for the local part only", fake, original)
+		}
+	}
+	if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+		t.Errorf("round trip = %q", got)
+	}
+}
+
+func TestEmailModeInvalid(t *testing.T) {
+	_, err := NewMasker(Config{Profiles: []string{ProfileContact}, EmailMode: "user"})
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Field != "email_mode" || ce.Detector != DetectorEmail {
+		t.Errorf("error = %v, want an email_mode ConfigError", err)
+	}
+}
@@ -0,0 +1,263 @@
+package safe_paste
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// candidate is a validated match waiting for overlap resolution
+type candidate struct {
+	start, end int
+	rank       int // index of the detector in priority order, lower wins
+	detector   Detector
+	keep       bool // preserved: claims its text but is not replaced
+}
+
+// Mask collects the matches of every detector, resolves overlaps and
+// rewrites the input in a single pass, so no detector sees another one's tokens.
+func (m *Masker) Mask(input string) MaskResult {
+	var candidates []candidate
+	for rank, d := range m.detectors {
+		for _, match := range d.FindMatches(input) {
+			if match[0] >= match[1] || !d.Validate(input[match[0]:match[1]]) {
+				continue
+			}
+			keep := m.preserve.keep(d, input[match[0]:match[1]])
+			whole := candidate{match[0], match[1], rank, d, keep}
+			for _, c := range m.splitCandidate(whole, input) {
+				candidates = append(candidates, wholeWords(c, input))
+			}
+		}
+	}
+
+	// Higher priority wins, then the longer match, then the earlier one
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.rank != b.rank {
+			return a.rank < b.rank
+		}
+		if a.end-a.start != b.end-b.start {
+			return a.end-a.start > b.end-b.start
+		}
+		return a.start < b.start
+	})
+	taken := make([]bool, len(input))
+	// Nothing is masked inside keyword exceptions
+	for _, r := range m.preserve.exceptionRanges(input) {
+		for i := r[0]; i < r[1]; i++ {
+			taken[i] = true
+		}
+	}
+	var accepted []candidate
+	for _, c := range candidates {
+		free := true
+		for i := c.start; i < c.end; i++ {
+			if taken[i] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		for i := c.start; i < c.end; i++ {
+			taken[i] = true
+		}
+		if !c.keep {
+			accepted = append(accepted, c)
+		}
+	}
+	sort.Slice(accepted, func(i, j int) bool { return accepted[i].start < accepted[j].start })
+
+	st := newMaskState(m, input)
+	var out strings.Builder
+	spans := make([]Span, 0, len(accepted))
+	last := 0
+	for _, c := range accepted {
+		original := input[c.start:c.end]
+		masked := st.replace(c.detector, original)
+		out.WriteString(input[last:c.start])
+		maskedStart := out.Len()
+		out.WriteString(masked)
+		spans = append(spans, Span{
+			Start:       c.start,
+			End:         c.end,
+			MaskedStart: maskedStart,
+			MaskedEnd:   out.Len(),
+			Detector:    c.detector.Name(),
+			Original:    original,
+			Token:       masked,
+		})
+		last = c.end
+	}
+	out.WriteString(input[last:])
+
+	return MaskResult{
+		MaskedText: out.String(),
+		Mapping:    st.mapping,
+		Spans:      spans,
+	}
+}
+
+// splitCandidate turns a match of a Splitter into one candidate per masked part.
+// Kept parts still claim their text unless they are Open, and so do masked
+// parts on the preserve list, e.g. a preserved IP as the host of a URL.
+func (m *Masker) splitCandidate(c candidate, input string) []candidate {
+	s, ok := c.detector.(Splitter)
+	if !ok || c.keep {
+		return []candidate{c}
+	}
+	parts := s.Split(input[c.start:c.end])
+	if parts == nil {
+		return []candidate{c}
+	}
+	var split []candidate
+	offset := c.start
+	for _, p := range parts {
+		if input[offset:min(offset+len(p.Text), len(input))] != p.Text {
+			// The parts do not add up to the match: mask it as a whole
+			return []candidate{c}
+		}
+		end := offset + len(p.Text)
+		switch {
+		case p.Text == "" || p.Detector == nil && p.Open:
+		case p.Detector == nil:
+			split = append(split, candidate{offset, end, c.rank, c.detector, true})
+		default:
+			split = append(split, candidate{offset, end, c.rank, p.Detector, m.preserve.keep(p.Detector, p.Text)})
+		}
+		offset = end
+	}
+	if offset != c.end {
+		return []candidate{c}
+	}
+	return split
+}
+
+// wholeWords extends a masked candidate that starts or ends inside a word to
+// the whole word, e.g. a keyword in "acme_prod" or "db-acme2". A token glued to
+// word characters would not be unmasked, or would unmask as another token (kw12).
+func wholeWords(c candidate, input string) candidate {
+	if c.keep {
+		return c
+	}
+	for c.start > 0 && isWordByte(input[c.start-1]) && isWordByte(input[c.start]) {
+		c.start--
+	}
+	for c.end < len(input) && isWordByte(input[c.end]) && isWordByte(input[c.end-1]) {
+		c.end++
+	}
+	return c
+}
+
+// maskState hands out tokens and pseudonyms during one Mask call
+type maskState struct {
+	m        *Masker
+	input    string
+	tokens   map[string]map[string]string // prefix -> original -> masked
+	counters map[string]int               // prefix -> last used token number
+	pseudo   map[string]int               // detector -> last used pseudonym number
+	mapping  map[string]string            // masked -> original
+	reserved map[string]bool              // token lookalikes already in the input
+}
+
+func newMaskState(m *Masker, input string) *maskState {
+	st := &maskState{
+		m:        m,
+		input:    input,
+		tokens:   make(map[string]map[string]string),
+		counters: make(map[string]int),
+		pseudo:   make(map[string]int),
+		mapping:  make(map[string]string),
+		reserved: make(map[string]bool),
+	}
+	// Token lookalikes already in the input are skipped, so they are never unmasked
+	for _, loc := range m.scheme.FindAll(input) {
+		st.reserved[input[loc[0]:loc[1]]] = true
+	}
+	// and so are the forms fuzzy unmask restores, e.g. "IP1" or "ip_1" for ip1
+	for _, loc := range m.scheme.fuzzy.FindAllStringSubmatchIndex(input, -1) {
+		prefix, id := input[loc[2]:loc[3]], input[loc[6]:loc[7]]
+		st.reserved[m.scheme.Format(strings.ToLower(prefix), strings.ToLower(id))] = true
+	}
+	return st
+}
+
+// replace returns the replacement for original, creating it on first use.
+// The same original always gets the same replacement within one prefix.
+func (st *maskState) replace(d Detector, original string) string {
+	prefix := d.TokenPrefix()
+	if st.tokens[prefix] == nil {
+		st.tokens[prefix] = make(map[string]string)
+	}
+	if masked := st.tokens[prefix][original]; masked != "" {
+		return masked
+	}
+
+	var masked string
+	// Parts of split matches may name a detector that is not enabled: tokens then
+	if p, ok := d.(Pseudonymizer); ok && st.m.modes[d.Name()] != "" && st.m.modes[d.Name()] != ModeToken {
+		masked = st.pseudonym(p, d.Name(), original)
+	}
+	if masked == "" {
+		masked = st.token(prefix, original)
+	}
+	st.tokens[prefix][original] = masked
+	st.mapping[masked] = original
+	return masked
+}
+
+// token returns the token for original: the next free number for prefix
+// (ip3), or a keyed id derived from the value (ip_3fa81c).
+func (st *maskState) token(prefix, original string) string {
+	if st.m.secret != nil {
+		return st.keyedToken(prefix, original)
+	}
+	for {
+		st.counters[prefix]++
+		token := st.m.scheme.Format(prefix, strconv.Itoa(st.counters[prefix]))
+		if !st.reserved[token] {
+			return token
+		}
+	}
+}
+
+// keyedToken derives the id from an HMAC of prefix and original, so the same
+// value gets the same token in every session. On a collision the id grows.
+func (st *maskState) keyedToken(prefix, original string) string {
+	mac := hmac.New(sha256.New, st.m.secret)
+	mac.Write([]byte(prefix + "\x00" + original))
+	id := hex.EncodeToString(mac.Sum(nil))
+	for n := keyedIDLength; ; n += 2 {
+		token := st.m.scheme.Format(prefix, id[:n])
+		if _, used := st.mapping[token]; n >= len(id) || !used && !st.reserved[token] {
+			return token
+		}
+	}
+}
+
+// maxPseudonymTries bounds the search for a pseudonym that is not in the input
+const maxPseudonymTries = 1000
+
+// pseudonym returns the next fake value that does not appear in the input
+// and is not used yet, or "" to fall back to a token.
+func (st *maskState) pseudonym(p Pseudonymizer, name, original string) string {
+	var previous string
+	for try := 0; try < maxPseudonymTries; try++ {
+		st.pseudo[name]++
+		fake := p.Pseudonym(original, st.pseudo[name])
+		if fake == "" || fake == previous {
+			// Deterministic pseudonyms have no other candidate
+			return ""
+		}
+		previous = fake
+		if _, used := st.mapping[fake]; !used && !strings.Contains(st.input, fake) {
+			return fake
+		}
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package safe_paste
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// DetectorEntropy finds high-entropy strings such as unknown API keys.
+// It is disabled by default; enable it in Config.Detectors.
+const DetectorEntropy = "entropy"
+
+// Character sets for EntropyConfig.Charsets
+const (
+	CharsetBase64    = "base64"    // A-Z a-z 0-9 + /
+	CharsetBase64URL = "base64url" // A-Z a-z 0-9 - _
+	CharsetHex       = "hex"       // 0-9 a-f
+)
+
+// EntropyConfig tunes the entropy detector
+type EntropyConfig struct {
+	// MinLength is the shortest run that is scored (default 20)
+	MinLength int `json:"min_length,omitempty"`
+	// Base64Threshold is the Shannon entropy in bits per character a base64 run needs (default 4.0)
+	Base64Threshold float64 `json:"base64_threshold,omitempty"`
+	// HexThreshold is the entropy a hex run needs (default 3.0)
+	HexThreshold float64 `json:"hex_threshold,omitempty"`
+	// Charsets lists the alphabets to look for (default base64, base64url and hex)
+	Charsets []string `json:"charsets,omitempty"`
+	// MinClasses is how many of lower case, upper case and digits a base64 run
+	// must mix (default 3), so words, paths and identifiers are skipped
+	MinClasses int `json:"min_classes,omitempty"`
+}
+
+var (
+	errUnknownCharset = errors.New(`must be "base64", "base64url" or "hex"`)
+	errNegative       = errors.New("must not be negative")
+)
+
+var charsetClasses = map[string]string{
+	CharsetBase64:    `A-Za-z0-9+/`,
+	CharsetBase64URL: `A-Za-z0-9_\-`,
+	CharsetHex:       `0-9a-fA-F`,
+}
+
+func init() {
+	RegisterDetector(DetectorSpec{Name: DetectorEntropy, Priority: 450, Disabled: true, New: newEntropyDetector})
+}
+
+// entropyDetector scores runs of base64/hex-like characters by Shannon entropy and length
+type entropyDetector struct {
+	cfg EntropyConfig
+	re  *regexp.Regexp
+	hex bool // hex runs are scored with HexThreshold
+}
+
+func newEntropyDetector(cfg Config) (Detector, error) {
+	ec := cfg.Entropy
+	if ec.MinLength < 0 {
+		return nil, &ConfigError{Field: "entropy.min_length", Err: errNegative}
+	}
+	if ec.Base64Threshold < 0 || ec.HexThreshold < 0 {
+		return nil, &ConfigError{Field: "entropy.threshold", Err: errNegative}
+	}
+	if ec.MinLength == 0 {
+		ec.MinLength = 20
+	}
+	if ec.Base64Threshold == 0 {
+		ec.Base64Threshold = 4.0
+	}
+	if ec.HexThreshold == 0 {
+		ec.HexThreshold = 3.0
+	}
+	if ec.MinClasses == 0 {
+		ec.MinClasses = 3
+	}
+	if len(ec.Charsets) == 0 {
+		ec.Charsets = []string{CharsetBase64, CharsetBase64URL, CharsetHex}
+	}
+
+	d := &entropyDetector{cfg: ec}
+	var class strings.Builder
+	for _, charset := range ec.Charsets {
+		chars, ok := charsetClasses[charset]
+		if !ok {
+			return nil, &ConfigError{Field: "entropy.charsets", Value: charset, Err: errUnknownCharset}
+		}
+		class.WriteString(chars)
+		d.hex = d.hex || charset == CharsetHex
+	}
+	d.re = regexp.MustCompile(`[` + class.String() + `]+={0,2}`)
+	return d, nil
+}
+
+func (d *entropyDetector) Name() string        { return DetectorEntropy }
+func (d *entropyDetector) TokenPrefix() string { return "secret" }
+
+func (d *entropyDetector) FindMatches(text string) [][]int {
+	var matches [][]int
+	for _, m := range d.re.FindAllStringIndex(text, -1) {
+		if m[1]-m[0] >= d.cfg.MinLength {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func (d *entropyDetector) Validate(candidate string) bool {
+	run := strings.TrimRight(candidate, "=")
+	if d.hex && isHexString(run) {
+		// A hex run must mix letters and digits, so long numbers are skipped
+		if strings.IndexAny(run, "0123456789") < 0 || strings.IndexAny(run, "abcdefABCDEF") < 0 {
+			return false
+		}
+		return shannonEntropy(run) >= d.cfg.HexThreshold
+	}
+	return charClasses(run) >= d.cfg.MinClasses && shannonEntropy(run) >= d.cfg.Base64Threshold
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	entropy := 0.0
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(len(s))
+			entropy -= p * math.Log2(p)
+		}
+	}
+	return entropy
+}
+
+// charClasses counts which of lower case, upper case and digits appear in s
+func charClasses(s string) int {
+	var lower, upper, digit int
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= 'a' && c <= 'z':
+			lower = 1
+		case c >= 'A' && c <= 'Z':
+			upper = 1
+		case c >= '0' && c <= '9':
+			digit = 1
+		}
+	}
+	return lower + upper + digit
+}
+
+func isHexString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
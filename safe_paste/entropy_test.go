@@ -0,0 +1,80 @@
+package safe_paste
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestShannonEntropy(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+	}{
+		{"", 0},
+		{"aaaa", 0},
+		{"abab", 1},
+		{"0123456789abcdef", 4},
+	}
+	for _, tt := range tests {
+		if got := shannonEntropy(tt.s); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("shannonEntropy(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMaskEntropy(t *testing.T) {
+	on := true
+	cfg := Config{Detectors: map[string]DetectorConfig{DetectorEntropy: {Enabled: &on}}}
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{"key=Zk3x9QpL7vR2mW8tYb4NcH6jD1sF", "key=secret1"},
+		{"sha 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b", "sha secret1"},
+		{"tok dGhpc2lzYVNlY3JldDEyMzQ1Ng==;", "tok secret1;"},
+		{"path /usr/lib/x86_64-linux-gnu/libc.so", "path /usr/lib/x86_64-linux-gnu/libc.so"},
+		{"call getUserAccountSettingsByIdentifier()", "call getUserAccountSettingsByIdentifier()"},
+		{"order 12345678901234567890123", "order 12345678901234567890123"},
+		{"short Zk3x9QpL7v", "short Zk3x9QpL7v"},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, cfg, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != tt.input {
+			t.Errorf("round trip of %q = %q", tt.input, got)
+		}
+	}
+
+	if got := maskWithConfig(t, Config{}, "key=Zk3x9QpL7vR2mW8tYb4NcH6jD1sF").MaskedText; got != "key=Zk3x9QpL7vR2mW8tYb4NcH6jD1sF" {
+		t.Errorf("entropy detector is enabled by default: %q", got)
+	}
+}
+
+func TestEntropyConfig(t *testing.T) {
+	on := true
+	detectors := map[string]DetectorConfig{DetectorEntropy: {Enabled: &on}}
+
+	// Only hex runs, with a lower length limit
+	cfg := Config{Detectors: detectors, Entropy: EntropyConfig{MinLength: 12, Charsets: []string{CharsetHex}}}
+	input := "id a1b2c3d4e5f60798 and Zk3x9QpL7vR2mW8tYb4NcH6jD1sF"
+	if got, want := maskWithConfig(t, cfg, input).MaskedText, "id secret1 and Zk3x9QpL7vR2mW8tYb4NcH6jD1sF"; got != want {
+		t.Errorf("hex only: Mask = %q, want %q", got, want)
+	}
+
+	// A threshold above the maximum entropy of the run masks nothing
+	cfg = Config{Detectors: detectors, Entropy: EntropyConfig{Base64Threshold: 6}}
+	if got := maskWithConfig(t, cfg, "Zk3x9QpL7vR2mW8tYb4NcH6jD1sF").MaskedText; got != "Zk3x9QpL7vR2mW8tYb4NcH6jD1sF" {
+		t.Errorf("high threshold: Mask = %q", got)
+	}
+
+	for _, ec := range []EntropyConfig{{Charsets: []string{"base32"}}, {MinLength: -1}, {HexThreshold: -1}} {
+		_, err := NewMasker(Config{Detectors: detectors, Entropy: ec})
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Detector != DetectorEntropy {
+			t.Errorf("EntropyConfig %+v: error = %v, want an entropy ConfigError", ec, err)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package safe_paste
+
+import (
+	"errors"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+)
+
+// Hardware identifier detector names
+const (
+	DetectorMAC    = "mac"
+	DetectorWWN    = "wwn"
+	DetectorSerial = "serial"
+)
+
+// MAC modes for Config.MACMode
+const (
+	MACWhole = "whole" // 00:1a:2b:3c:4d:5e -> mac1 (default)
+	MACOUI   = "oui"   // 00:1a:2b:3c:4d:5e -> 00:1a:2b:nic1, keeping the vendor prefix
+)
+
+var (
+	// Colon, dash and Cisco dotted forms with the same separator throughout; EUI-64 has eight bytes
+	macRegex = regexp.MustCompile(`(?i)\b(?:[0-9a-f]{2}(?::[0-9a-f]{2}){5}(?:(?::[0-9a-f]{2}){2})?|[0-9a-f]{2}(?:-[0-9a-f]{2}){5}(?:(?:-[0-9a-f]{2}){2})?|[0-9a-f]{4}\.[0-9a-f]{4}\.[0-9a-f]{4})\b`)
+	// Eight colon-separated bytes, or 16 hex digits after a WWN label
+	wwnRegex = regexp.MustCompile(`(?i)\b(?P<value>[0-9a-f]{2}(?::[0-9a-f]{2}){7})\b|\b(?:wwn|wwpn|wwnn|port_name|node_name)\s*[:=]?\s*(?P<value>(?:0x)?[0-9a-f]{16})\b`)
+	// Serial numbers only count after a label, e.g. "Serial Number: FCW2233L0AB" or "SN: FOC1234X0AB"
+	serialRegex = regexp.MustCompile(`(?i)\b(?:serial(?:[ _-]?(?:number|no|num))?|s/n|sn)\.?\s*[:=#]\s*(?P<value>[a-z0-9][a-z0-9-]{4,31})\b`)
+
+	errMACMode = errors.New(`must be "whole" or "oui"`)
+)
+
+func init() {
+	// Above IPv6, which would take eight colon-separated bytes for an address
+	RegisterDetector(DetectorSpec{Name: DetectorWWN, Priority: 320, New: newWWNDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorMAC, Priority: 310, New: newMACDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorSerial, Priority: 250, New: newSerialDetector})
+}
+
+// macDetector masks MAC addresses as a whole or keeps their OUI (vendor prefix)
+type macDetector struct {
+	regexDetector
+	nic Detector // device part in MACOUI mode, nil masks the whole address
+}
+
+func newMACDetector(cfg Config) (Detector, error) {
+	d := &macDetector{regexDetector: regexDetector{name: DetectorMAC, prefix: "mac", re: macRegex, validate: isMAC, pseudonym: pseudonymHex}}
+	switch cfg.MACMode {
+	case "", MACWhole:
+	case MACOUI:
+		d.nic = &regexDetector{name: DetectorMAC, prefix: "nic", pseudonym: pseudonymHex}
+	default:
+		return nil, &ConfigError{Field: "mac_mode", Value: cfg.MACMode, Err: errMACMode}
+	}
+	return d, nil
+}
+
+// FindMatches skips MACs that are part of a longer run of hex groups
+func (d *macDetector) FindMatches(text string) [][]int {
+	var matches [][]int
+	for _, m := range d.re.FindAllStringIndex(text, -1) {
+		if !hexGroupAt(text, m[0]-2, m[0]-1) && !hexGroupAt(text, m[1]+1, m[1]) {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// hexGroupAt reports whether text has a ':', '-' or '.' at sep next to a hex digit at digit
+func hexGroupAt(text string, digit, sep int) bool {
+	if min(digit, sep) < 0 || max(digit, sep) >= len(text) {
+		return false
+	}
+	return strings.IndexByte(":-.", text[sep]) >= 0 && isHexDigit(text[digit])
+}
+
+// Split keeps the OUI in MACOUI mode: 00:1a:2b: stays, 3c:4d:5e is masked.
+// In the Cisco form the OUI ends inside a group, so those are masked whole.
+func (d *macDetector) Split(mac string) []Part {
+	if d.nic == nil || mac[4] == '.' {
+		return nil
+	}
+	oui := len("00:1a:2b:")
+	return []Part{{Text: mac[:oui]}, {Text: mac[oui:], Detector: d.nic}}
+}
+
+// isMAC rejects the all-zero and broadcast addresses, which identify nothing
+func isMAC(s string) bool {
+	hex := strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(s))
+	return hex != "000000000000" && hex != "ffffffffffff"
+}
+
+func newWWNDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorWWN, prefix: "wwn", re: wwnRegex, validate: isWWN, pseudonym: pseudonymHex}, nil
+}
+
+// isWWN checks the NAA (first nibble) of a world wide name: 1, 2, 5, 6 or C
+func isWWN(s string) bool {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	switch s[0] {
+	case '1', '2', '5', '6', 'c':
+		return true
+	}
+	return false
+}
+
+func newSerialDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorSerial, prefix: "serial", re: serialRegex, validate: isSerial, pseudonym: pseudonymName}, nil
+}
+
+// isSerial needs a digit, so labels followed by words ("Serial Number: Not Specified") are skipped
+func isSerial(s string) bool {
+	return strings.IndexAny(s, "0123456789") >= 0
+}
+
+// pseudonymHex replaces every hex digit with a random one of the same case,
+// keeping the separators, so a MAC stays a MAC
+func pseudonymHex(original string, n int) string {
+	rng := rand.New(rand.NewPCG(uint64(n), uint64(len(original))))
+	b := []byte(original)
+	for i, c := range b {
+		switch {
+		case c >= '0' && c <= '9' || c >= 'a' && c <= 'f':
+			b[i] = "0123456789abcdef"[rng.IntN(16)]
+		case c >= 'A' && c <= 'F':
+			b[i] = "0123456789ABCDEF"[rng.IntN(16)]
+		}
+	}
+	return string(b)
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
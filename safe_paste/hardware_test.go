@@ -0,0 +1,55 @@
+package safe_paste
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaskHardwareIdentifiers(t *testing.T) {
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{"eth0 ether 00:1A:2B:3C:4D:5E brd ff:ff:ff:ff:ff:ff", "eth0 ether mac1 brd ff:ff:ff:ff:ff:ff"},
+		{"dash 00-1a-2b-3c-4d-5e cisco 001a.2b3c.4d5e", "dash mac1 cisco mac2"},
+		{"mixed 00:1a-2b:3c-4d:5e", "mixed 00:1a-2b:3c-4d:5e"},
+		{"port 50:06:01:60:3b:a0:12:34 ok", "port wwn1 ok"},
+		{"port_name = 0x21000024ff3c8a9e", "port_name = wwn1"},
+		{"eui 00:1a:2b:ff:fe:3c:4d:5e", "eui mac1"},
+		{"PID: C9300-48P, VID: V02, SN: FOC1234X0AB", "PID: C9300-48P, VID: V02, SN: serial1"},
+		{"Serial Number: Not Specified", "Serial Number: Not Specified"},
+		{"serial_no=ABC-12345 again ABC-12345", "serial_no=serial1 again ABC-12345"},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, Config{}, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != tt.input {
+			t.Errorf("round trip of %q = %q", tt.input, got)
+		}
+	}
+}
+
+func TestMaskMACKeepsOUI(t *testing.T) {
+	input := "a 00:1a:2b:3c:4d:5e b 00-1A-2B-3C-4D-5F c 001a.2b3c.4d5e"
+	result := maskWithConfig(t, Config{MACMode: MACOUI}, input)
+	if want := "a 00:1a:2b:nic1 b 00-1A-2B-nic2 c mac1"; result.MaskedText != want {
+		t.Errorf("Mask = %q, want %q", result.MaskedText, want)
+	}
+	if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+		t.Errorf("round trip = %q", got)
+	}
+
+	result = maskWithConfig(t, Config{MACMode: MACOUI, MaskingMode: ModePseudonym}, "ether 00:1a:2b:3c:4d:5e")
+	if fake := strings.TrimPrefix(result.MaskedText, "ether "); !strings.HasPrefix(fake, "00:1a:2b:") || !macRegex.MatchString(fake) || fake == "00:1a:2b:3c:4d:5e" {
+		t.Errorf("pseudonym = %q, want a MAC with the same OUI", result.MaskedText)
+	}
+
+	_, err := NewMasker(Config{MACMode: "vendor"})
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Field != "mac_mode" {
+		t.Errorf("invalid mac_mode: error = %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package safe_paste
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// Hostname modes for Config.HostnameMode
+const (
+	HostnameWhole     = "whole"     // db01.eu.corp.example.com -> hostname1 (default)
+	HostnameSubdomain = "subdomain" // db01.eu.corp.example.com -> hostname1.corp.example.com
+	HostnameLabels    = "labels"    // db01.eu.corp.example.com -> label1.label2.corp.example.com
+)
+
+var (
+	// Dotted names; only those under an internal domain are masked
+	fqdnRegex   = regexp.MustCompile(`(?i)\b[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)+\b`)
+	domainRegex = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)*$`)
+
+	errHostnameMode  = errors.New(`must be "whole", "subdomain" or "labels"`)
+	errInvalidDomain = errors.New("not a domain name")
+	errPublicSuffix  = errors.New("is a public suffix; list your own domain under it instead")
+)
+
+// publicSuffixes are registry and hosting suffixes under which anyone can
+// register a name. Listing one as an internal domain would mask every public
+// site under it (github.com under "com"), so it is rejected.
+var publicSuffixes = map[string]bool{
+	"com": true, "net": true, "org": true, "edu": true, "gov": true, "mil": true, "int": true,
+	"io": true, "co": true, "dev": true, "app": true, "ai": true, "cloud": true, "info": true,
+	"biz": true, "me": true, "xyz": true, "online": true, "site": true, "tech": true,
+	"eu": true, "us": true, "uk": true, "de": true, "fr": true, "nl": true, "ch": true,
+	"tr": true, "jp": true, "cn": true, "ru": true, "in": true, "br": true, "au": true, "ca": true,
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true,
+	"com.tr": true, "net.tr": true, "org.tr": true, "gen.tr": true, "edu.tr": true, "gov.tr": true,
+	"co.jp": true, "com.au": true, "com.br": true, "com.cn": true,
+	"github.io": true, "gitlab.io": true, "herokuapp.com": true, "azurewebsites.net": true,
+	"cloudfront.net": true, "amazonaws.com": true, "appspot.com": true, "blogspot.com": true,
+	"vercel.app": true, "netlify.app": true, "pages.dev": true, "workers.dev": true,
+}
+
+// hostnameMatcher tells internal hostnames apart: those matching
+// hostname_pattern and those under one of the internal domains
+type hostnameMatcher struct {
+	re      *regexp.Regexp // nil if hostname_pattern is empty
+	domains []string       // lower case, without leading dots
+}
+
+func newHostnameMatcher(cfg Config) (*hostnameMatcher, error) {
+	h := &hostnameMatcher{}
+	if cfg.HostnamePattern != "" {
+		re, err := regexp.Compile(cfg.HostnamePattern)
+		if err != nil {
+			return nil, &ConfigError{Field: "hostname_pattern", Value: cfg.HostnamePattern, Err: err}
+		}
+		h.re = re
+	}
+	for _, domain := range cfg.InternalDomains {
+		d := strings.Trim(strings.ToLower(domain), ".")
+		if !domainRegex.MatchString(d) {
+			return nil, &ConfigError{Field: "internal_domains", Value: domain, Err: errInvalidDomain}
+		}
+		if publicSuffixes[d] {
+			return nil, &ConfigError{Field: "internal_domains", Value: domain, Err: errPublicSuffix}
+		}
+		h.domains = append(h.domains, d)
+	}
+	return h, nil
+}
+
+// find returns the matches of hostname_pattern and the names under an internal domain
+func (h *hostnameMatcher) find(text string) [][]int {
+	var matches [][]int
+	if h.re != nil {
+		matches = h.re.FindAllStringIndex(text, -1)
+	}
+	if len(h.domains) > 0 {
+		for _, m := range fqdnRegex.FindAllStringIndex(text, -1) {
+			if h.suffix(text[m[0]:m[1]]) != "" {
+				matches = append(matches, m)
+			}
+		}
+	}
+	return matches
+}
+
+// suffix returns the longest internal domain name lies under, as written in
+// name, or "" if there is none. The domain alone is not a hostname.
+func (h *hostnameMatcher) suffix(name string) string {
+	lower := strings.ToLower(name)
+	var longest string
+	for _, d := range h.domains {
+		if len(lower) > len(d)+1 && strings.HasSuffix(lower, "."+d) && len(d) > len(longest) {
+			longest = d
+		}
+	}
+	if longest == "" {
+		return ""
+	}
+	return name[len(name)-len(longest):]
+}
+
+// internal reports whether the whole host is one the hostname detector masks
+func (h *hostnameMatcher) internal(host string) bool {
+	if h.suffix(host) != "" {
+		return true
+	}
+	if h.re == nil {
+		return false
+	}
+	m := h.re.FindStringIndex(host)
+	return m != nil && m[0] == 0 && m[1] == len(host)
+}
+
+// keptDomains returns the internal domains the subdomain and labels modes keep
+func keptDomains(cfg Config) []string {
+	if cfg.HostnameMode != HostnameSubdomain && cfg.HostnameMode != HostnameLabels {
+		return nil
+	}
+	hosts, err := newHostnameMatcher(cfg)
+	if err != nil {
+		return nil
+	}
+	return hosts.domains
+}
+
+// hostnameDetector masks internal hostnames as a whole, or only the labels
+// in front of their internal domain
+type hostnameDetector struct {
+	regexDetector
+	hosts     *hostnameMatcher
+	mode      string
+	subdomain Detector // the labels in front of the domain as one part
+	label     Detector // each label on its own
+}
+
+func newHostnameDetector(cfg Config) (Detector, error) {
+	d, err := newHostnames(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// newHostnames builds the hostname detector, which the URL detector shares
+func newHostnames(cfg Config) (*hostnameDetector, error) {
+	hosts, err := newHostnameMatcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.HostnameMode {
+	case "", HostnameWhole, HostnameSubdomain, HostnameLabels:
+	default:
+		return nil, &ConfigError{Field: "hostname_mode", Value: cfg.HostnameMode, Err: errHostnameMode}
+	}
+	return &hostnameDetector{
+		regexDetector: regexDetector{name: DetectorHostname, prefix: "hostname", pseudonym: pseudonymName},
+		hosts:         hosts,
+		mode:          cfg.HostnameMode,
+		subdomain:     &regexDetector{name: DetectorHostname, prefix: "hostname", pseudonym: pseudonymName},
+		label:         &regexDetector{name: DetectorHostname, prefix: "label", pseudonym: pseudonymName},
+	}, nil
+}
+
+func (d *hostnameDetector) FindMatches(text string) [][]int { return d.hosts.find(text) }
+func (d *hostnameDetector) Validate(candidate string) bool  { return true }
+
+// Split keeps the internal domain in the subdomain and labels modes
+func (d *hostnameDetector) Split(name string) []Part {
+	suffix := d.hosts.suffix(name)
+	if suffix == "" || d.mode == "" || d.mode == HostnameWhole {
+		return nil
+	}
+	sub := name[:len(name)-len(suffix)-1]
+	var parts []Part
+	if d.mode == HostnameSubdomain {
+		parts = append(parts, Part{Text: sub, Detector: d.subdomain})
+	} else {
+		for i, label := range strings.Split(sub, ".") {
+			if i > 0 {
+				parts = append(parts, Part{Text: "."})
+			}
+			parts = append(parts, Part{Text: label, Detector: d.label})
+		}
+	}
+	return append(parts, Part{Text: "." + suffix})
+}
@@ -0,0 +1,87 @@
+package safe_paste
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaskInternalDomains(t *testing.T) {
+	input := "db01.eu.corp.example.com and api.eu.corp.example.com talk to cache.prod, not github.com or corp.example.com"
+	tests := []struct {
+		mode   string
+		masked string
+	}{
+		{"", "hostname1 and hostname2 talk to hostname3, not github.com or corp.example.com"},
+		{HostnameWhole, "hostname1 and hostname2 talk to hostname3, not github.com or corp.example.com"},
+		{HostnameSubdomain, "hostname1.corp.example.com and hostname2.corp.example.com talk to hostname3.prod, not github.com or corp.example.com"},
+		{HostnameLabels, "label1.label2.corp.example.com and label3.label2.corp.example.com talk to label4.prod, not github.com or corp.example.com"},
+	}
+	for _, tt := range tests {
+		cfg := Config{InternalDomains: []string{"corp.example.com", ".prod"}, HostnameMode: tt.mode}
+		m, err := NewMasker(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := m.Mask(input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("mode %q: Mask = %q, want %q", tt.mode, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+			t.Errorf("mode %q: round trip = %q", tt.mode, got)
+		}
+		// The kept domains are not taken for domains added by the AI
+		opts := UnmaskOptions{Fuzzy: true, Domains: m.KeptDomains()}
+		if got, report := UnmaskTextWithReport(result.MaskedText, result.Mapping, opts); got != input || len(report.Fuzzy) != 0 {
+			t.Errorf("mode %q: fuzzy round trip = %q, fuzzy matches %+v", tt.mode, got, report.Fuzzy)
+		}
+	}
+	m, err := NewMasker(Config{InternalDomains: []string{"corp.example.com"}, HostnameMode: HostnameSubdomain})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := UnmaskOptions{Fuzzy: true, Domains: m.KeptDomains()}
+	if _, report := UnmaskTextWithReport("ssh hostname1.example.com", map[string]string{"hostname1": "db01"}, opts); len(report.Fuzzy) != 1 {
+		t.Errorf("added domain: fuzzy matches %+v, want one", report.Fuzzy)
+	}
+}
+
+func TestInternalDomainsWithPattern(t *testing.T) {
+	cfg := Config{
+		HostnamePattern: `\bxy-[a-z0-9-]+\b`,
+		InternalDomains: []string{"Internal"},
+		HostnameMode:    HostnameSubdomain,
+	}
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{"xy-db01 and Vault.INTERNAL", "hostname1 and hostname2.INTERNAL"},
+		{"see https://vault.internal/ui and https://example.org/", "see https://hostname1.internal/ui and https://example.org/"},
+		{"version 1.2.3 of internal.tools", "version 1.2.3 of internal.tools"},
+	}
+	for _, tt := range tests {
+		if got := maskWithConfig(t, cfg, tt.input).MaskedText; got != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, got, tt.masked)
+		}
+	}
+}
+
+func TestInternalDomainsInvalid(t *testing.T) {
+	tests := []Config{
+		{InternalDomains: []string{"com"}},
+		{InternalDomains: []string{".co.uk"}},
+		{InternalDomains: []string{"github.io"}},
+		{InternalDomains: []string{"corp example"}},
+		{HostnameMode: "host"},
+	}
+	for _, cfg := range tests {
+		_, err := NewMasker(cfg)
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Detector != DetectorHostname {
+			t.Errorf("NewMasker(%+v) error = %v, want a hostname ConfigError", cfg, err)
+		}
+	}
+	if _, err := NewMasker(Config{InternalDomains: []string{"acme.github.io", "lan"}}); err != nil {
+		t.Errorf("domains under a public suffix: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package safe_paste
+
+import (
+	"errors"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ipv4Pattern = `(?:[0-9]{1,3}\.){3}[0-9]{1,3}`
+	// Full and compressed forms, also with an embedded IPv4 address (::ffff:10.0.0.1)
+	ipv6Pattern = `(?:(?:[0-9a-fA-F]{0,4}:){2,6}` + ipv4Pattern + `|(?:[0-9a-fA-F]{0,4}:){2,7}[0-9a-fA-F]{0,4})`
+	zonePattern = `%[0-9A-Za-z_.-]+`
+
+	// IPv4 with an optional CIDR length, range end or port:
+	// 10.0.0.0/16, 10.0.0.1-10.0.0.9, 10.0.0.1:8080
+	ipv4UnitPattern = `(?P<addr>` + ipv4Pattern + `)(?:/(?P<bits>[0-9]{1,3})|-(?P<end>` + ipv4Pattern + `)|:(?P<port>[0-9]{1,5}))?`
+	// IPv6 in brackets with an optional zone and port: [fe80::1%eth0]:443
+	ipv6BracketPattern = `\[(?P<addr>` + ipv6Pattern + `)(?P<zone>` + zonePattern + `)?\](?::(?P<port>[0-9]{1,5})\b)?`
+	// Bare IPv6 with an optional CIDR length, zone or range end: 2001:db8::/32, fe80::1%eth0
+	ipv6BarePattern = `(?P<addr>` + ipv6Pattern + `)(?:/(?P<bits>[0-9]{1,3})|(?P<zone>` + zonePattern + `)|-(?P<end>` + ipv6Pattern + `))?`
+
+	// maxPort is the highest valid port in an ip:port unit
+	maxPort = 65535
+)
+
+var (
+	ipv4Regex     = regexp.MustCompile(`\b` + ipv4UnitPattern + `\b`)
+	ipv4UnitRegex = regexp.MustCompile(`^(?:` + ipv4UnitPattern + `)$`)
+	ipv6Regex     = regexp.MustCompile(ipv6BracketPattern + `|` + ipv6BarePattern)
+	ipv6UnitRegex = regexp.MustCompile(`^(?:` + ipv6BracketPattern + `|` + ipv6BarePattern + `)$`)
+	// The start of an IPv6 address in front of an embedded IPv4 address, e.g. "::ffff:"
+	ipv6HeadRegex = regexp.MustCompile(`(?:^|[^0-9A-Za-z:])((?:[0-9a-fA-F]{0,4}:){2,6})$`)
+)
+
+// IP address classes for Config.IPClasses
+const (
+	IPClassLoopback      = "loopback"      // 127.0.0.0/8, ::1
+	IPClassUnspecified   = "unspecified"   // 0.0.0.0, ::
+	IPClassLinkLocal     = "link_local"    // 169.254.0.0/16, fe80::/10
+	IPClassDocumentation = "documentation" // RFC 5737, 2001:db8::/32
+)
+
+// Actions for Config.IPClasses
+const (
+	IPClassMask     = "mask"
+	IPClassPreserve = "preserve"
+)
+
+// defaultIPClasses keeps loopback and unspecified addresses readable, as the
+// old localhost skip list did.
+var defaultIPClasses = map[string]string{
+	IPClassLoopback:      IPClassPreserve,
+	IPClassUnspecified:   IPClassPreserve,
+	IPClassLinkLocal:     IPClassMask,
+	IPClassDocumentation: IPClassMask,
+}
+
+var (
+	documentationPrefixes = mustPrefixes("192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32")
+
+	errUnknownIPClass = errors.New("unknown IP class")
+	errIPClassAction  = errors.New(`must be "mask" or "preserve"`)
+)
+
+// ipClass returns the class of addr, or "" for an ordinary address.
+// IPv4-mapped addresses are classified as their IPv4 address.
+func ipClass(addr netip.Addr) string {
+	addr = addr.Unmap()
+	switch {
+	case addr.IsLoopback():
+		return IPClassLoopback
+	case addr.IsUnspecified():
+		return IPClassUnspecified
+	case addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast():
+		return IPClassLinkLocal
+	case inAny(documentationPrefixes, addr):
+		return IPClassDocumentation
+	}
+	return ""
+}
+
+// preservedIPClasses returns the classes that are not masked, applying
+// Config.IPClasses over the defaults.
+func preservedIPClasses(cfg Config) (map[string]bool, error) {
+	preserved := make(map[string]bool)
+	for class, action := range defaultIPClasses {
+		preserved[class] = action == IPClassPreserve
+	}
+	for class, action := range cfg.IPClasses {
+		if _, ok := defaultIPClasses[class]; !ok {
+			return nil, &ConfigError{Field: "ip_classes", Value: class, Err: errUnknownIPClass}
+		}
+		switch action {
+		case IPClassMask, IPClassPreserve:
+			preserved[class] = action == IPClassPreserve
+		default:
+			return nil, &ConfigError{Field: "ip_classes", Value: class + ": " + action, Err: errIPClassAction}
+		}
+	}
+	return preserved, nil
+}
+
+// ipDetector matches IP addresses together with their CIDR length, range end,
+// zone or port, so "10.0.0.0/16" is masked as one unit and its mapping entry
+// restores the exact text. Ports and brackets are split off and kept.
+type ipDetector struct {
+	name      string
+	v6        bool
+	re        *regexp.Regexp // finds units in text
+	unit      *regexp.Regexp // the same, anchored, to split a unit
+	maxBits   int
+	validAddr func(addr string) bool
+	pseudonym func(addr string, n int) string // fake value for one address
+}
+
+// ipUnit is a parsed IP unit
+type ipUnit struct {
+	addrs [][]int // offsets of the addresses, two for a range
+	bits  int     // CIDR length, -1 without one
+}
+
+func (d *ipDetector) Name() string        { return d.name }
+func (d *ipDetector) TokenPrefix() string { return "ip" }
+
+func (d *ipDetector) FindMatches(text string) [][]int {
+	var matches [][]int
+	names := d.re.SubexpNames()
+	for _, m := range d.re.FindAllStringSubmatchIndex(text, -1) {
+		if !d.atBoundary(text, m[0], m[1]) {
+			continue
+		}
+		if _, ok := d.parse(text[m[0]:m[1]]); ok {
+			matches = append(matches, m[:2])
+			continue
+		}
+		// A bad suffix such as "/99" or ":99999" leaves the addresses themselves
+		for i, name := range names {
+			if (name == "addr" || name == "end") && m[2*i] >= 0 {
+				matches = append(matches, []int{m[2*i], m[2*i+1]})
+			}
+		}
+	}
+	return matches
+}
+
+// atBoundary rejects matches glued to other text, such as the "12:34:56" of
+// "x12:34:56", and IPv4 addresses embedded in IPv6 ones, which the IPv6 detector masks.
+func (d *ipDetector) atBoundary(text string, start, end int) bool {
+	if !d.v6 {
+		head := ipv6HeadRegex.FindStringSubmatchIndex(text[max(0, start-40):start])
+		if head == nil {
+			return true
+		}
+		_, err := netip.ParseAddr(text[max(0, start-40)+head[2] : end])
+		return err != nil
+	}
+	if text[start] == '[' {
+		return true
+	}
+	if start > 0 && (isWordByte(text[start-1]) || text[start-1] == ':') {
+		return false
+	}
+	if end < len(text) {
+		next := text[end]
+		if isWordByte(next) || next == ':' || next == '.' && end+1 < len(text) && isDigit(text[end+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (d *ipDetector) Validate(candidate string) bool {
+	_, ok := d.parse(candidate)
+	return ok
+}
+
+// parse splits a unit into its addresses and CIDR length and validates every part
+func (d *ipDetector) parse(unit string) (ipUnit, bool) {
+	m := d.unit.FindStringSubmatchIndex(unit)
+	if m == nil {
+		return ipUnit{}, false
+	}
+	u := ipUnit{bits: -1}
+	for i, name := range d.unit.SubexpNames() {
+		start, end := m[2*i], m[2*i+1]
+		if start < 0 {
+			continue
+		}
+		value := unit[start:end]
+		switch name {
+		case "addr", "end":
+			if !d.validAddr(value) {
+				return ipUnit{}, false
+			}
+			u.addrs = append(u.addrs, []int{start, end})
+		case "bits":
+			if u.bits, _ = strconv.Atoi(value); u.bits > d.maxBits {
+				return ipUnit{}, false
+			}
+		case "port":
+			if port, _ := strconv.Atoi(value); port > maxPort {
+				return ipUnit{}, false
+			}
+		}
+	}
+	if len(u.addrs) == 2 {
+		first, err1 := netip.ParseAddr(unit[u.addrs[0][0]:u.addrs[0][1]])
+		last, err2 := netip.ParseAddr(unit[u.addrs[1][0]:u.addrs[1][1]])
+		if err1 == nil && err2 == nil && last.Less(first) {
+			return ipUnit{}, false
+		}
+	}
+	return u, true
+}
+
+// Split masks only the address of "10.0.0.1:8080" and "[fe80::1%eth0]:443" and
+// keeps the port and brackets, as URL hosts do, so a host gets the same token
+// with any port. CIDR blocks and ranges stay one unit.
+func (d *ipDetector) Split(unit string) []Part {
+	u, ok := d.parse(unit)
+	if !ok || len(u.addrs) != 1 || u.bits >= 0 {
+		return nil
+	}
+	start, end := u.addrs[0][0], u.addrs[0][1]
+	switch {
+	case unit[0] == '[':
+		// The zone belongs to the address, as in "fe80::1%eth0"
+		start, end = 1, strings.IndexByte(unit, ']')
+	case !strings.HasPrefix(unit[end:], ":"):
+		return nil
+	}
+	return []Part{{Text: unit[:start]}, {Text: unit[start:end], Detector: d}, {Text: unit[end:]}}
+}
+
+// prefixes returns the addresses of a unit, or its CIDR block.
+// IPv4-mapped addresses are returned as IPv4.
+func (d *ipDetector) prefixes(match string) []netip.Prefix {
+	u, ok := d.parse(match)
+	if !ok {
+		return nil
+	}
+	var prefixes []netip.Prefix
+	for _, loc := range u.addrs {
+		addr, err := netip.ParseAddr(match[loc[0]:loc[1]])
+		if err != nil {
+			return nil
+		}
+		bits := addr.BitLen()
+		if u.bits >= 0 {
+			bits = u.bits
+		}
+		if addr.Is4In6() {
+			addr, bits = addr.Unmap(), max(bits-96, 0)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, bits))
+	}
+	return prefixes
+}
+
+// Pseudonym replaces every address of the unit and keeps the rest, so
+// "10.1.0.0/16" stays a network and "[fe80::1%eth0]:443" keeps zone and port.
+func (d *ipDetector) Pseudonym(original string, n int) string {
+	u, ok := d.parse(original)
+	if !ok || d.pseudonym == nil {
+		return ""
+	}
+	var b strings.Builder
+	var fakes []string
+	last := 0
+	for _, loc := range u.addrs {
+		addr := original[loc[0]:loc[1]]
+		fake := d.pseudonym(addr, n)
+		if fake == "" {
+			return ""
+		}
+		if u.bits >= 0 {
+			fake = networkOf(addr, fake, u.bits)
+		}
+		b.WriteString(original[last:loc[0]])
+		b.WriteString(fake)
+		fakes = append(fakes, fake)
+		last = loc[1]
+	}
+	b.WriteString(original[last:])
+	// Both ends of a range got the same value, a token says more
+	if len(fakes) == 2 && fakes[0] == fakes[1] {
+		return ""
+	}
+	return b.String()
+}
+
+// networkOf clears the host bits of fake if addr is a network address for bits
+func networkOf(addr, fake string, bits int) string {
+	a, err1 := netip.ParseAddr(addr)
+	f, err2 := netip.ParseAddr(fake)
+	if err1 != nil || err2 != nil || a.Is4() != f.Is4() {
+		return fake
+	}
+	network, err := a.Prefix(bits)
+	if err != nil || network.Addr() != a {
+		return fake
+	}
+	masked, err := f.Prefix(bits)
+	if err != nil {
+		return fake
+	}
+	return masked.Addr().String()
+}
@@ -0,0 +1,122 @@
+package safe_paste
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestMaskIPUnits(t *testing.T) {
+	tests := []struct {
+		input  string
+		masked string
+		unit   string // ip1
+	}{
+		{"allow 10.0.0.0/16;", "allow ip1;", "10.0.0.0/16"},
+		{"pool 192.168.1.10-192.168.1.20 ok", "pool ip1 ok", "192.168.1.10-192.168.1.20"},
+		{"listen 10.0.0.1:8080", "listen ip1:8080", "10.0.0.1"},
+		// The port is kept, so a host has one token with any port
+		{"10.0.0.1, 10.0.0.1:80 and 10.0.0.1:443", "ip1, ip1:80 and ip1:443", "10.0.0.1"},
+		{"route 2001:db8::/32 via", "route ip1 via", "2001:db8::/32"},
+		{"connect [2001:db8::1]:443 now", "connect [ip1]:443 now", "2001:db8::1"},
+		// Inside a URL too, sharing the token of the bare address
+		{"curl https://[2001:db8::1]:443/ 2001:db8::1", "curl https://[ip1]:443/ ip1", "2001:db8::1"},
+		{"ping [fe80::1%eth0] and fe80::1%eth0", "ping [ip1] and ip1", "fe80::1%eth0"},
+		{"ping fe80::1%eth0 now", "ping ip1 now", "fe80::1%eth0"},
+		{"bad 10.0.0.1/40 mask", "bad ip1/40 mask", "10.0.0.1"},
+		{"bad 10.0.0.1:99999 port", "bad ip1:99999 port", "10.0.0.1"},
+		{"reversed 10.0.0.9-10.0.0.1", "reversed ip1-ip2", "10.0.0.9"},
+		{"loopback 127.0.0.1:8080", "loopback 127.0.0.1:8080", ""},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, Config{}, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, result.MaskedText, tt.masked)
+		}
+		if tt.unit != "" && result.Mapping["ip1"] != tt.unit {
+			t.Errorf("Mask(%q) mapped ip1 to %q, want %q", tt.input, result.Mapping["ip1"], tt.unit)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != tt.input {
+			t.Errorf("round trip of %q = %q", tt.input, got)
+		}
+	}
+}
+
+func TestIPUnitPseudonyms(t *testing.T) {
+	tests := []struct {
+		mode  string
+		input string
+		want  func(masked string) bool
+	}{
+		{ModePseudonym, "net 10.1.0.0/16", func(m string) bool { return m == "net 192.0.0.0/16" }},
+		{ModePseudonym, "host 10.1.2.3:22", func(m string) bool { return m == "host 192.0.2.1:22" }},
+		{ModePseudonym, "pool 10.0.0.1-10.0.0.9", func(m string) bool { return m == "pool ip1" }},
+		{ModePrefixPreserving, "net 10.1.0.0/16", func(m string) bool {
+			return strings.HasPrefix(m, "net 10.") && strings.HasSuffix(m, ".0.0/16") && m != "net 10.1.0.0/16"
+		}},
+		{ModePrefixPreserving, "ping [fe80::1%eth0]:443", func(m string) bool {
+			addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(m, "ping ["), "%eth0]:443"))
+			return err == nil && netip.MustParsePrefix("fe80::/10").Contains(addr) && addr.String() != "fe80::1"
+		}},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, Config{MaskingMode: tt.mode, IPAnonymizationKey: "test key"}, tt.input)
+		if !tt.want(result.MaskedText) {
+			t.Errorf("%s: Mask(%q) = %q", tt.mode, tt.input, result.MaskedText)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != tt.input {
+			t.Errorf("%s: round trip of %q = %q", tt.mode, tt.input, got)
+		}
+	}
+}
+
+func TestMaskIPv6Validation(t *testing.T) {
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{"at 12:34:56 the job ran", "at 12:34:56 the job ran"},
+		{"mac 00:1a:2b:3c:4d:5e", "mac mac1"},
+		{"dst 2001:db8:85a3::8a2e:370:7334.", "dst ip1."},
+		{"peer ::ffff:10.0.0.1 up", "peer ip1 up"},
+		{"local ::1 and ::", "local ::1 and ::"},
+		{"gw fe80::1 and 10.0.0.1", "gw ip1 and ip2"},
+		{"host:10.0.0.1", "host:ip1"},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, Config{}, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, result.MaskedText, tt.masked)
+		}
+	}
+	if got := maskWithConfig(t, Config{}, "peer ::ffff:10.0.0.1").Mapping["ip1"]; got != "::ffff:10.0.0.1" {
+		t.Errorf("IPv4-mapped address mapped to %q, want the whole address", got)
+	}
+}
+
+func TestMaskIPClasses(t *testing.T) {
+	input := "lo 127.0.0.2 ::1 ll 169.254.1.1 fe80::2 doc 192.0.2.7"
+	tests := []struct {
+		classes map[string]string
+		masked  string
+	}{
+		{nil, "lo 127.0.0.2 ::1 ll ip1 ip2 doc ip3"},
+		{map[string]string{IPClassLoopback: IPClassMask}, "lo ip1 ip2 ll ip3 ip4 doc ip5"},
+		{map[string]string{IPClassLinkLocal: IPClassPreserve, IPClassDocumentation: IPClassPreserve},
+			"lo 127.0.0.2 ::1 ll 169.254.1.1 fe80::2 doc 192.0.2.7"},
+	}
+	for _, tt := range tests {
+		if got := maskWithConfig(t, Config{IPClasses: tt.classes}, input).MaskedText; got != tt.masked {
+			t.Errorf("IPClasses %v: Mask = %q, want %q", tt.classes, got, tt.masked)
+		}
+	}
+
+	for _, classes := range []map[string]string{{"private": IPClassMask}, {IPClassLoopback: "hide"}} {
+		_, err := NewMasker(Config{IPClasses: classes})
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Field != "ip_classes" {
+			t.Errorf("IPClasses %v: error = %v, want an ip_classes ConfigError", classes, err)
+		}
+	}
+}
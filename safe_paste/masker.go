@@ -2,33 +2,77 @@ package safe_paste
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type Config struct {
 	Keywords        []string `json:"keywords"`
 	HostnamePattern string   `json:"hostname_pattern"`
 	Theme           string   `json:"theme"` // "light" or "dark"
+	// InternalDomains are domain suffixes whose hostnames are masked, e.g. "corp.example.com", ".prod"
+	InternalDomains []string `json:"internal_domains,omitempty"`
+	// HostnameMode is "whole" (hostname1, default), "subdomain" (hostname1.corp.example.com)
+	// or "labels" (label1.label2.corp.example.com) for hostnames under an internal domain
+	HostnameMode string `json:"hostname_mode,omitempty"`
+	// Detectors enables/disables built-in or custom detectors and sets their priority
+	Detectors map[string]DetectorConfig `json:"detectors,omitempty"`
+	// Profiles enable groups of detectors: "pii", "contact" (email, phone), "payment" (cards, IBANs)
+	Profiles []string `json:"profiles,omitempty"`
+	// EmailMode is "whole" (email1, default), "local" (user1@example.com),
+	// "domain" (alice@domain1) or "hostname" (user1@hostname1)
+	EmailMode string `json:"email_mode,omitempty"`
+	// MACMode is "whole" (mac1, default) or "oui" (00:1a:2b:nic1, keeps the vendor prefix)
+	MACMode string `json:"mac_mode,omitempty"`
+	// TokenFormat is a preset ("plain", "brackets", "angle", "underscore") or a template like "⟦{TYPE}_{n}⟧"
+	TokenFormat string `json:"token_format,omitempty"`
+	// TokenIDs is "sequential" (ip1, default) or "keyed" (ip_3fa81c, the same value
+	// always gets the same token); keyed ids need TokenSecret
+	TokenIDs string `json:"token_ids,omitempty"`
+	// TokenSecret is the team secret keyed token ids are derived from
+	TokenSecret string `json:"token_secret,omitempty"`
+	// MaskingMode is "token" (ip1, default), "pseudonym" (realistic fake values)
+	// or "prefix_preserving" (IPs keep their subnets and class)
+	MaskingMode string `json:"masking_mode,omitempty"`
+	// IPClasses masks or preserves special addresses, e.g. {"loopback": "preserve"}.
+	// Classes: loopback, unspecified, link_local, documentation.
+	IPClasses map[string]string `json:"ip_classes,omitempty"`
+	// IPAnonymizationKey seeds the prefix-preserving mode; empty means a random key per run
+	IPAnonymizationKey string `json:"ip_anonymization_key,omitempty"`
+	// URL selects the URL components that are masked (host, path segments, query parameters)
+	URL URLConfig `json:"url,omitzero"`
+	// Entropy tunes the entropy detector (thresholds, length, character sets)
+	Entropy EntropyConfig `json:"entropy,omitzero"`
+	// Preserve lists values, networks, hostname patterns and keyword exceptions that are never masked
+	Preserve PreserveConfig `json:"preserve,omitzero"`
+	// SessionTTLHours is how long saved sessions are kept (0 = 7 days, negative = forever)
+	SessionTTLHours int `json:"session_ttl_hours,omitempty"`
 }
 
 // MaskResult holds both the masked text and the mapping for unmasking
 type MaskResult struct {
 	MaskedText string
 	Mapping    map[string]string // masked -> original (e.g., "ip1" -> "192.168.1.100")
+	Spans      []Span            // every replaced value, in order of appearance
 }
 
-var (
-	// IPv4: matches xxx.xxx.xxx.xxx
-	ipv4Regex = regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)
-	// IPv6: matches full and compressed forms
-	ipv6Regex    = regexp.MustCompile(`\b(?:[0-9a-fA-F]{0,4}:){2,7}[0-9a-fA-F]{0,4}\b`)
-	localhostIPs = []string{"127.0.0.1", "localhost", "0.0.0.0", "::1", "::"}
-)
+// Span describes one replaced value, for highlighting and auditing
+type Span struct {
+	Start       int // byte offset in the original input
+	End         int
+	MaskedStart int // byte offset in MaskedText
+	MaskedEnd   int
+	Detector    string
+	Original    string
+	Token       string
+}
 
 // isValidIPv4 checks if an IPv4 address has valid octets (0-255)
 func isValidIPv4(ip string) bool {
@@ -45,8 +89,8 @@ func isValidIPv4(ip string) bool {
 	return true
 }
 
-// getConfigPath exe ile aynı dizinde config.json bulur (portable)
-func getConfigPath() string {
+// ConfigPath exe ile aynı dizinde config.json bulur (portable)
+func ConfigPath() string {
 	exePath, err := os.Executable()
 	if err == nil {
 		exeDir := filepath.Dir(exePath)
@@ -58,28 +102,54 @@ func getConfigPath() string {
 	return "config.json" // fallback: mevcut dizin
 }
 
-func LoadConfig() Config {
-	configPath := getConfigPath()
-	data, err := os.ReadFile(configPath)
+// DefaultConfig is used when config.json cannot be read
+func DefaultConfig() Config {
+	return Config{
+		Keywords:        []string{},
+		HostnamePattern: "\\bxy-[a-z0-9.-]+\\b",
+		Theme:           "light",
+	}
+}
+
+// LoadConfigFile reads a config file, reporting read and JSON errors
+func LoadConfigFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Println("Failed to load config:", configPath)
-		return Config{
-			Keywords:        []string{},
-			HostnamePattern: "\\bxy-[a-z0-9.-]+\\b",
-			Theme:           "light",
-		}
+		return Config{}, err
 	}
 	var cfg Config
-	json.Unmarshal(data, &cfg)
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
 	if cfg.Theme == "" {
 		cfg.Theme = "light"
 	}
-	// fmt.Printf("Loaded config from: %s\nHostname pattern: %s\nKeywords: %v\n", configPath, cfg.HostnamePattern, cfg.Keywords)
+	return cfg, nil
+}
+
+// LoadConfig reads config.json, falling back to DefaultConfig if it is missing or invalid.
+// Messages go to stderr so stdout stays clean for the CLI.
+func LoadConfig() Config {
+	cfg, err := loadDefaultConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to load config:", err)
+		return DefaultConfig()
+	}
 	return cfg
 }
 
+// loadDefaultConfig reads config.json. Only a missing file yields DefaultConfig;
+// a file that cannot be read or parsed is an error, not an empty Config.
+func loadDefaultConfig() (Config, error) {
+	cfg, err := LoadConfigFile(ConfigPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return DefaultConfig(), nil
+	}
+	return cfg, err
+}
+
 func SaveConfig(cfg Config) error {
-	configPath := getConfigPath()
+	configPath := ConfigPath()
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
@@ -87,108 +157,103 @@ func SaveConfig(cfg Config) error {
 	return os.WriteFile(configPath, data, 0644)
 }
 
-func MaskText(input string) string {
-	result := MaskTextWithMapping(input)
-	return result.MaskedText
+// Masker holds the compiled detectors of a Config.
+// It is safe for concurrent use by multiple goroutines.
+type Masker struct {
+	detectors []Detector
+	modes     map[string]string // detector name -> ModeToken or ModePseudonym
+	scheme    *TokenScheme
+	secret    []byte // HMAC key of keyed token ids, nil for sequential ids
+	preserve  *preserver
+	domains   []string // internal domains kept by hostname_mode
 }
 
-// MaskTextWithMapping returns both masked text and the mapping
-func MaskTextWithMapping(input string) MaskResult {
-	cfg := LoadConfig()
-	hostnameRegex := regexp.MustCompile(cfg.HostnamePattern)
-	ipMap := make(map[string]string)
-	hostnameMap := make(map[string]string)
-	keywordMap := make(map[string]string)
-	reverseMapping := make(map[string]string) // masked -> original
-
-	ipCounter, hostnameCounter, keywordCounter := 1, 1, 1
-
-	// IPv4 replace (do this first to avoid conflicts)
-	ipv4s := ipv4Regex.FindAllString(input, -1)
-	for _, ip := range ipv4s {
-		// Skip localhost IPs
-		skip := false
-		for _, local := range localhostIPs {
-			if ip == local {
-				skip = true
-				break
-			}
-		}
-		if skip {
-			continue
-		}
-		// Skip invalid IPs (e.g., 256.256.256.256)
-		if !isValidIPv4(ip) {
-			continue
-		}
-		if ipMap[ip] == "" {
-			masked := fmt.Sprintf("ip%d", ipCounter)
-			ipMap[ip] = masked
-			reverseMapping[masked] = ip
-			ipCounter++
-		}
-		input = strings.ReplaceAll(input, ip, ipMap[ip])
-	}
-
-	// IPv6 replace
-	ipv6s := ipv6Regex.FindAllString(input, -1)
-	for _, ip := range ipv6s {
-		// Skip localhost IPv6
-		skip := false
-		for _, local := range localhostIPs {
-			if ip == local {
-				skip = true
-				break
-			}
-		}
-		if skip {
-			continue
-		}
-		if ipMap[ip] == "" {
-			masked := fmt.Sprintf("ip%d", ipCounter)
-			ipMap[ip] = masked
-			reverseMapping[masked] = ip
-			ipCounter++
-		}
-		input = strings.ReplaceAll(input, ip, ipMap[ip])
+// NewMasker validates the config and compiles all of its rules once.
+// Invalid rules are reported as *ConfigError.
+func NewMasker(cfg Config) (*Masker, error) {
+	scheme, secret, err := tokenSchemeFor(cfg)
+	if err != nil {
+		return nil, err
 	}
-
-	// Hostnames replace
-	hostnames := hostnameRegex.FindAllString(input, -1)
-	for _, hn := range hostnames {
-		if hostnameMap[hn] == "" {
-			masked := fmt.Sprintf("hostname%d", hostnameCounter)
-			hostnameMap[hn] = masked
-			reverseMapping[masked] = hn
-			hostnameCounter++
-		}
-		input = strings.ReplaceAll(input, hn, hostnameMap[hn])
+	detectors, err := buildDetectors(cfg)
+	if err != nil {
+		return nil, err
+	}
+	modes, err := detectorModes(cfg, detectors)
+	if err != nil {
+		return nil, err
 	}
+	preserve, err := newPreserver(cfg.Preserve)
+	if err != nil {
+		return nil, err
+	}
+	return &Masker{detectors: detectors, modes: modes, scheme: scheme, secret: secret, preserve: preserve, domains: keptDomains(cfg)}, nil
+}
 
-	// Keywords replace (do this last to catch remaining sensitive words)
-	for _, kw := range cfg.Keywords {
-		if keywordMap[kw] == "" {
-			masked := fmt.Sprintf("kw%d", keywordCounter)
-			keywordMap[kw] = masked
-			reverseMapping[masked] = kw
-			keywordCounter++
-		}
-		input = strings.ReplaceAll(input, kw, keywordMap[kw])
+// TokenScheme returns the scheme used to build tokens
+func (m *Masker) TokenScheme() *TokenScheme {
+	return m.scheme
+}
+
+// KeptDomains returns the internal domains left in place after masked labels
+// (hostname_mode "subdomain" or "labels"), for UnmaskOptions.Domains
+func (m *Masker) KeptDomains() []string {
+	return m.domains
+}
+
+var (
+	defaultMu     sync.Mutex
+	defaultMasker *Masker
+	defaultStamp  time.Time // config.json modification time used for defaultMasker
+)
+
+// DefaultMasker returns a Masker for config.json, rebuilt only when the file changes
+func DefaultMasker() (*Masker, error) {
+	var stamp time.Time
+	if info, err := os.Stat(ConfigPath()); err == nil {
+		stamp = info.ModTime()
 	}
 
-	return MaskResult{
-		MaskedText: input,
-		Mapping:    reverseMapping,
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultMasker != nil && stamp.Equal(defaultStamp) {
+		return defaultMasker, nil
 	}
+	cfg, err := loadDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	m, err := NewMasker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defaultMasker, defaultStamp = m, stamp
+	return m, nil
 }
 
-// UnmaskText replaces masked values (ip1, hostname1, kw1) with original values
-func UnmaskText(maskedText string, mapping map[string]string) string {
-	result := maskedText
-	// Replace in reverse order: keywords, hostnames, then IPs
-	// This ensures longer patterns are replaced first
-	for masked, original := range mapping {
-		result = strings.ReplaceAll(result, masked, original)
+func MaskText(input string) string {
+	result := MaskTextWithMapping(input)
+	return result.MaskedText
+}
+
+// MaskTextWithMapping returns both masked text and the mapping.
+// If config.json is invalid it returns an empty result rather than masking
+// with the built-in defaults, which would leak the configured keywords and
+// hostnames. Use MaskDefault to get the error.
+func MaskTextWithMapping(input string) MaskResult {
+	result, err := MaskDefault(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Invalid config, nothing masked:", err)
+		return MaskResult{}
 	}
 	return result
 }
+
+// MaskDefault masks input with DefaultMasker, returning its config error
+func MaskDefault(input string) (MaskResult, error) {
+	m, err := DefaultMasker()
+	if err != nil {
+		return MaskResult{}, err
+	}
+	return m.Mask(input), nil
+}
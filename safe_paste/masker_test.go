@@ -1,6 +1,11 @@
 package safe_paste
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -139,3 +144,164 @@ func TestAIWorkflow(t *testing.T) {
 		t.Errorf("AI Workflow failed.\nGot: %s\nWant: %s", unmaskedResponse, expected)
 	}
 }
+
+func maskWithConfig(t *testing.T, cfg Config, input string) MaskResult {
+	t.Helper()
+	m, err := NewMasker(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.Mask(input)
+}
+
+func TestMaskSinglePass(t *testing.T) {
+	cfg := Config{
+		Keywords:        []string{"ip", "backend"},
+		HostnamePattern: `\bxy-[a-z0-9.-]+\b`,
+	}
+	tests := []struct {
+		name           string
+		input          string
+		expectedMasked string
+	}{
+		{
+			name:           "Keyword does not corrupt earlier tokens",
+			input:          "ip of 10.0.0.1",
+			expectedMasked: "kw1 of ip1",
+		},
+		{
+			name:           "Substring IP is not rewritten inside a longer IP",
+			input:          "110.0.0.12 and 10.0.0.1",
+			expectedMasked: "ip1 and ip2",
+		},
+		{
+			name:           "Higher priority hostname wins over keyword inside it",
+			input:          "xy-backend and backend",
+			expectedMasked: "hostname1 and kw1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := maskWithConfig(t, cfg, tt.input)
+			if result.MaskedText != tt.expectedMasked {
+				t.Errorf("MaskedText = %v, want %v", result.MaskedText, tt.expectedMasked)
+			}
+		})
+	}
+}
+
+func TestMaskKeywordInsideIdentifier(t *testing.T) {
+	keywords := []string{"acme"}
+	var words []string
+	for i := 2; i <= 12; i++ {
+		kw := fmt.Sprintf("team%c", 'a'+i)
+		keywords = append(keywords, kw)
+		words = append(words, kw)
+	}
+	input := strings.Join(words, " ") + " acme_prod and acmeCorp and db-acme2 and acme"
+	result := maskWithConfig(t, Config{Keywords: keywords}, input)
+	for _, token := range []string{"kw12", "kw13", "kw14", "kw15"} {
+		if !strings.Contains(result.MaskedText, token) {
+			t.Errorf("MaskedText = %q, want %s", result.MaskedText, token)
+		}
+	}
+	if strings.Contains(result.MaskedText, "acme") {
+		t.Errorf("MaskedText = %q, want the keyword masked inside identifiers", result.MaskedText)
+	}
+	if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+		t.Errorf("round trip = %q, want %q", got, input)
+	}
+}
+
+func TestMaskSpans(t *testing.T) {
+	cfg := Config{HostnamePattern: `\bxy-[a-z0-9.-]+\b`}
+	input := "Check xy-backend at 192.168.1.50"
+	result := maskWithConfig(t, cfg, input)
+
+	if len(result.Spans) != 2 {
+		t.Fatalf("Spans = %+v, want 2 spans", result.Spans)
+	}
+	for _, span := range result.Spans {
+		if got := input[span.Start:span.End]; got != span.Original {
+			t.Errorf("input[%d:%d] = %q, want %q", span.Start, span.End, got, span.Original)
+		}
+		if got := result.MaskedText[span.MaskedStart:span.MaskedEnd]; got != span.Token {
+			t.Errorf("MaskedText[%d:%d] = %q, want %q", span.MaskedStart, span.MaskedEnd, got, span.Token)
+		}
+	}
+	if result.Spans[0].Detector != DetectorHostname || result.Spans[1].Detector != DetectorIPv4 {
+		t.Errorf("Spans detectors = %s, %s", result.Spans[0].Detector, result.Spans[1].Detector)
+	}
+}
+
+func TestNewMaskerErrors(t *testing.T) {
+	_, err := NewMasker(Config{HostnamePattern: `xy-(`})
+	var ce *ConfigError
+	if !errors.As(err, &ce) {
+		t.Fatalf("NewMasker error = %v, want *ConfigError", err)
+	}
+	if ce.Detector != DetectorHostname || ce.Field != "hostname_pattern" || ce.Value != `xy-(` {
+		t.Errorf("ConfigError = %+v", ce)
+	}
+
+	_, err = NewMasker(Config{Detectors: map[string]DetectorConfig{"nope": {}}})
+	if !errors.Is(err, ErrUnknownDetector) {
+		t.Errorf("NewMasker error = %v, want ErrUnknownDetector", err)
+	}
+}
+
+func TestDefaultMaskerConfigErrors(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// A missing config.json means the built-in defaults
+	if _, err := DefaultMasker(); err != nil {
+		t.Fatalf("DefaultMasker without config.json: %v", err)
+	}
+
+	// A malformed one is reported instead of masking with an empty Config
+	if err := os.WriteFile("config.json", []byte(`{"keywords": ["acme",]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DefaultMasker(); err == nil || !strings.Contains(err.Error(), "config.json") {
+		t.Errorf("DefaultMasker with malformed config.json: error = %v", err)
+	}
+	// and nothing is masked with the weaker built-in rules
+	if _, err := MaskDefault("acme at 10.0.0.1"); err == nil {
+		t.Error("MaskDefault with malformed config.json: no error")
+	}
+	if got := MaskTextWithMapping("acme at 10.0.0.1"); got.MaskedText != "" || got.Mapping != nil {
+		t.Errorf("MaskTextWithMapping with malformed config.json = %+v, want an empty result", got)
+	}
+
+	if err := os.WriteFile("config.json", []byte(`{"keywords": ["acme"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := DefaultMasker()
+	if err != nil {
+		t.Fatalf("DefaultMasker with valid config.json: %v", err)
+	}
+	if got := m.Mask("acme prod").MaskedText; got != "kw1 prod" {
+		t.Errorf("Mask = %q, want %q", got, "kw1 prod")
+	}
+}
+
+func TestMaskerConcurrentUse(t *testing.T) {
+	m, err := NewMasker(Config{HostnamePattern: `\bxy-[a-z0-9.-]+\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if got := m.Mask("xy-db at 10.0.0.1").MaskedText; got != "hostname1 at ip1" {
+					t.Errorf("MaskedText = %v", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,161 @@
+package safe_paste
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// PII detector names. They are disabled by default; enable them one by one in
+// Config.Detectors or together with Config.Profiles.
+const (
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorCreditCard = "credit_card"
+	DetectorIBAN       = "iban"
+	DetectorTCKimlik   = "tc_kimlik"
+)
+
+// Detector profiles for Config.Profiles
+const (
+	ProfilePII     = "pii"     // every PII detector
+	ProfileContact = "contact" // email and phone
+	ProfilePayment = "payment" // credit cards and IBANs
+)
+
+// detectorProfiles lists the detectors each profile enables
+var detectorProfiles = map[string][]string{
+	ProfilePII:     {DetectorEmail, DetectorPhone, DetectorCreditCard, DetectorIBAN, DetectorTCKimlik},
+	ProfileContact: {DetectorEmail, DetectorPhone},
+	ProfilePayment: {DetectorCreditCard, DetectorIBAN},
+}
+
+var errUnknownProfile = errors.New("unknown profile")
+
+// profileDetectors returns the detectors enabled by the profiles of cfg
+func profileDetectors(cfg Config) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for _, profile := range cfg.Profiles {
+		names, ok := detectorProfiles[profile]
+		if !ok {
+			return nil, &ConfigError{Field: "profiles", Value: profile, Err: errUnknownProfile}
+		}
+		for _, name := range names {
+			enabled[name] = true
+		}
+	}
+	return enabled, nil
+}
+
+var (
+	// E.164 (+90 532 123 45 67) or a local number with a trunk prefix (0532 123 45 67, (0212) 555-1234)
+	phoneRegex = regexp.MustCompile(`(?:\+[1-9][0-9]{0,3}|\(0[0-9]{2,4}\)|\b0[0-9]{2,4})(?:[ .-]?\(?[0-9]{2,4}\)?){2,4}\b`)
+	// 13 to 19 digits, optionally grouped with spaces or dashes
+	cardRegex     = regexp.MustCompile(`\b[0-9](?:[ -]?[0-9]){12,18}\b`)
+	ibanRegex     = regexp.MustCompile(`\b[A-Z]{2}[0-9]{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`)
+	tcKimlikRegex = regexp.MustCompile(`\b[1-9][0-9]{10}\b`)
+)
+
+func init() {
+	RegisterDetector(DetectorSpec{Name: DetectorIBAN, Priority: 370, Disabled: true, New: newIBANDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorCreditCard, Priority: 360, Disabled: true, New: newCreditCardDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorTCKimlik, Priority: 355, Disabled: true, New: newTCKimlikDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorEmail, Priority: 350, Disabled: true, New: newEmailDetector})
+	RegisterDetector(DetectorSpec{Name: DetectorPhone, Priority: 150, Disabled: true, New: newPhoneDetector})
+}
+
+func newPhoneDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorPhone, prefix: "phone", re: phoneRegex, validate: isPhoneNumber, pseudonym: pseudonymName}, nil
+}
+
+func newCreditCardDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorCreditCard, prefix: "card", re: cardRegex, validate: isCardNumber}, nil
+}
+
+func newIBANDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorIBAN, prefix: "iban", re: ibanRegex, validate: isIBAN}, nil
+}
+
+func newTCKimlikDetector(cfg Config) (Detector, error) {
+	return &regexDetector{name: DetectorTCKimlik, prefix: "tckn", re: tcKimlikRegex, validate: isTCKimlik}, nil
+}
+
+// digitsOf returns the digits of s, dropping separators
+func digitsOf(s string) []int {
+	var digits []int
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			digits = append(digits, int(s[i]-'0'))
+		}
+	}
+	return digits
+}
+
+// isPhoneNumber checks the digit count: 8 to 15 for E.164, 10 or 11 for local numbers
+func isPhoneNumber(s string) bool {
+	n := len(digitsOf(s))
+	if strings.HasPrefix(s, "+") {
+		return n >= 8 && n <= 15
+	}
+	return n == 10 || n == 11
+}
+
+// isCardNumber checks the Luhn checksum and rejects runs of a single digit
+func isCardNumber(s string) bool {
+	digits := digitsOf(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	same := true
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		same = same && digits[i] == digits[0]
+	}
+	return sum%10 == 0 && !same
+}
+
+// isIBAN checks the length and the ISO 13616 mod-97 checksum
+func isIBAN(s string) bool {
+	iban := strings.ReplaceAll(s, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	// Move the country code and check digits to the end, letters count as 10..35
+	remainder := 0
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A'+10)) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
+}
+
+// isTCKimlik checks the two check digits of a Turkish identity number
+func isTCKimlik(s string) bool {
+	d := digitsOf(s)
+	if len(d) != 11 || d[0] == 0 {
+		return false
+	}
+	odd := d[0] + d[2] + d[4] + d[6] + d[8]
+	even := d[1] + d[3] + d[5] + d[7]
+	if ((odd*7-even)%10+10)%10 != d[9] {
+		return false
+	}
+	sum := 0
+	for _, digit := range d[:10] {
+		sum += digit
+	}
+	return sum%10 == d[10]
+}
@@ -0,0 +1,82 @@
+package safe_paste
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPIIChecksums(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string) bool
+		value string
+		valid bool
+	}{
+		{"card", isCardNumber, "4111 1111 1111 1111", true},
+		{"card", isCardNumber, "4111-1111-1111-1112", false},
+		{"card", isCardNumber, "0000 0000 0000 0000", false},
+		{"iban", isIBAN, "GB82 WEST 1234 5698 7654 32", true},
+		{"iban", isIBAN, "GB82WEST12345698765433", false},
+		{"tc_kimlik", isTCKimlik, "10000000146", true},
+		{"tc_kimlik", isTCKimlik, "12345678901", false},
+		{"phone", isPhoneNumber, "+90 532 123 45 67", true},
+		{"phone", isPhoneNumber, "0532 123 45 67", true},
+		{"phone", isPhoneNumber, "0532 12", false},
+	}
+	for _, tt := range tests {
+		if got := tt.check(tt.value); got != tt.valid {
+			t.Errorf("%s check of %q = %v, want %v", tt.name, tt.value, got, tt.valid)
+		}
+	}
+}
+
+func TestMaskPII(t *testing.T) {
+	cfg := Config{Profiles: []string{ProfilePII}}
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{"from jane.doe+support@example.com:", "from email1:"},
+		{"call +90 532 123 45 67 or (0212) 555-1234", "call phone1 or phone2"},
+		{"card 4111 1111 1111 1111 exp", "card card1 exp"},
+		{"card 4111 1111 1111 1112 exp", "card 4111 1111 1111 1112 exp"},
+		{"iban GB82 WEST 1234 5698 7654 32.", "iban iban1."},
+		{"tckn 10000000146, order 12345678901", "tckn tckn1, order 12345678901"},
+		{"at 2024-01-15 10:20:30", "at 2024-01-15 10:20:30"},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, cfg, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != tt.input {
+			t.Errorf("round trip of %q = %q", tt.input, got)
+		}
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	input := "jane@example.com 4111 1111 1111 1111"
+	off := false
+	tests := []struct {
+		cfg    Config
+		masked string
+	}{
+		{Config{}, input},
+		{Config{Profiles: []string{ProfileContact}}, "email1 4111 1111 1111 1111"},
+		{Config{Profiles: []string{ProfilePayment}}, "jane@example.com card1"},
+		{Config{Profiles: []string{ProfilePII}, Detectors: map[string]DetectorConfig{DetectorEmail: {Enabled: &off}}},
+			"jane@example.com card1"},
+	}
+	for _, tt := range tests {
+		if got := maskWithConfig(t, tt.cfg, input).MaskedText; got != tt.masked {
+			t.Errorf("Profiles %v: Mask = %q, want %q", tt.cfg.Profiles, got, tt.masked)
+		}
+	}
+
+	_, err := NewMasker(Config{Profiles: []string{"gdpr"}})
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Field != "profiles" {
+		t.Errorf("unknown profile: error = %v, want a profiles ConfigError", err)
+	}
+}
@@ -0,0 +1,128 @@
+package safe_paste
+
+import (
+	"net/netip"
+	"regexp"
+	"strings"
+)
+
+// PreserveConfig lists values that are never masked, whichever detector finds them
+type PreserveConfig struct {
+	Values           []string `json:"values,omitempty"`            // exact values, e.g. "8.8.8.8"
+	Networks         []string `json:"networks,omitempty"`          // IPs or CIDR blocks, e.g. "198.51.100.0/24"
+	HostnamePatterns []string `json:"hostname_patterns,omitempty"` // regexes matching the whole value
+	Keywords         []string `json:"keywords,omitempty"`          // text in which nothing is masked
+}
+
+// preserver applies a PreserveConfig to detector matches
+type preserver struct {
+	values     map[string]bool
+	addrs      map[netip.Addr]bool // values that are IP addresses
+	networks   []netip.Prefix
+	hostnames  []*regexp.Regexp
+	exceptions []string
+}
+
+// addresser is implemented by detectors whose matches contain IP addresses
+type addresser interface {
+	// prefixes returns the addresses (as /32 or /128) or CIDR blocks of a match
+	prefixes(match string) []netip.Prefix
+}
+
+func newPreserver(cfg PreserveConfig) (*preserver, error) {
+	p := &preserver{values: make(map[string]bool), addrs: make(map[netip.Addr]bool)}
+	for _, v := range cfg.Values {
+		p.values[v] = true
+		if addr, err := netip.ParseAddr(v); err == nil {
+			p.addrs[addr.Unmap()] = true
+		}
+	}
+	for _, n := range cfg.Networks {
+		prefix, err := parseNetwork(n)
+		if err != nil {
+			return nil, &ConfigError{Field: "preserve.networks", Value: n, Err: err}
+		}
+		p.networks = append(p.networks, prefix)
+	}
+	for _, pattern := range cfg.HostnamePatterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, &ConfigError{Field: "preserve.hostname_patterns", Value: pattern, Err: err}
+		}
+		p.hostnames = append(p.hostnames, re)
+	}
+	for _, kw := range cfg.Keywords {
+		if kw != "" {
+			p.exceptions = append(p.exceptions, kw)
+		}
+	}
+	return p, nil
+}
+
+// parseNetwork accepts a CIDR block or a single address
+func parseNetwork(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// exceptionRanges returns the offsets of every keyword exception in text
+func (p *preserver) exceptionRanges(text string) [][]int {
+	var ranges [][]int
+	for _, kw := range p.exceptions {
+		for offset := 0; ; {
+			i := strings.Index(text[offset:], kw)
+			if i < 0 {
+				break
+			}
+			start := offset + i
+			ranges = append(ranges, []int{start, start + len(kw)})
+			offset = start + len(kw)
+		}
+	}
+	return ranges
+}
+
+// keep reports whether a match of d must stay as is
+func (p *preserver) keep(d Detector, match string) bool {
+	if p.values[match] {
+		return true
+	}
+	for _, re := range p.hostnames {
+		if re.MatchString(match) {
+			return true
+		}
+	}
+	// IP units (8.8.8.8:53, ranges) are kept if every address in them is
+	if a, ok := d.(addresser); ok && (len(p.networks) > 0 || len(p.addrs) > 0) {
+		prefixes := a.prefixes(match)
+		for _, prefix := range prefixes {
+			if !p.inNetworks(prefix) && !p.isValue(prefix) {
+				return false
+			}
+		}
+		return len(prefixes) > 0
+	}
+	return false
+}
+
+// isValue reports whether prefix is a single address listed in values
+func (p *preserver) isValue(prefix netip.Prefix) bool {
+	return prefix.IsSingleIP() && p.addrs[prefix.Addr().Unmap()]
+}
+
+// inNetworks reports whether prefix lies inside one of the preserved networks
+func (p *preserver) inNetworks(prefix netip.Prefix) bool {
+	for _, n := range p.networks {
+		if n.Bits() <= prefix.Bits() && n.Contains(prefix.Addr()) {
+			return true
+		}
+	}
+	return false
+}
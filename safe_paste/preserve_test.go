@@ -0,0 +1,57 @@
+package safe_paste
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMaskPreserve(t *testing.T) {
+	cfg := Config{
+		Keywords:        []string{"acme"},
+		HostnamePattern: `\bxy-[a-z0-9.-]+\b`,
+		Preserve: PreserveConfig{
+			Values:           []string{"8.8.8.8"},
+			Networks:         []string{"198.51.100.0/24", "2001:db8::/32", "10.9.9.9"},
+			HostnamePatterns: []string{`xy-status\..*`},
+			Keywords:         []string{"acme-public-docs"},
+		},
+	}
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{"dns 8.8.8.8 and 8.8.4.4", "dns 8.8.8.8 and ip1"},
+		{"dns 8.8.8.8:53 and 8.8.4.4:53", "dns 8.8.8.8:53 and ip1:53"},
+		{"net 8.8.8.0/24 and range 8.8.8.8-8.8.8.9", "net ip1 and range ip2"},
+		{"web 198.51.100.7 and 198.51.101.7", "web 198.51.100.7 and ip1"},
+		{"net 198.51.100.0/25 and 198.51.0.0/16", "net 198.51.100.0/25 and ip1"},
+		{"pool 198.51.100.1-198.51.100.9", "pool 198.51.100.1-198.51.100.9"},
+		{"v6 2001:db8::1 and ::ffff:10.9.9.9", "v6 2001:db8::1 and ::ffff:10.9.9.9"},
+		{"xy-status.prod and xy-db.prod", "xy-status.prod and hostname1"},
+		{"see acme-public-docs about acme", "see acme-public-docs about kw1"},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, cfg, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, result.MaskedText, tt.masked)
+		}
+	}
+}
+
+func TestPreserveConfigErrors(t *testing.T) {
+	tests := []struct {
+		preserve PreserveConfig
+		field    string
+	}{
+		{PreserveConfig{Networks: []string{"10.0.0.0/99"}}, "preserve.networks"},
+		{PreserveConfig{Networks: []string{"intranet"}}, "preserve.networks"},
+		{PreserveConfig{HostnamePatterns: []string{"xy-("}}, "preserve.hostname_patterns"},
+	}
+	for _, tt := range tests {
+		_, err := NewMasker(Config{Preserve: tt.preserve})
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Field != tt.field {
+			t.Errorf("NewMasker(%+v) error = %v, want a %s ConfigError", tt.preserve, err, tt.field)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package safe_paste
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"strings"
+	"unicode"
+)
+
+// Masking modes for Config.MaskingMode and DetectorConfig.Mode
+const (
+	ModeToken     = "token"     // opaque tokens: ip1, hostname1
+	ModePseudonym = "pseudonym" // realistic fake values of the same shape
+	// ModePrefixPreserving keeps subnets and address classes of IPs (Crypto-PAn);
+	// other detectors use tokens in this mode.
+	ModePrefixPreserving = "prefix_preserving"
+)
+
+var (
+	errUnknownMode     = errors.New("unknown masking mode")
+	errUnsupportedMode = errors.New("masking mode not supported by this detector")
+)
+
+// prefixPreservingDetectors support ModePrefixPreserving, the others use tokens
+// when it is the global masking mode.
+var prefixPreservingDetectors = map[string]bool{DetectorIPv4: true, DetectorIPv6: true}
+
+// Pseudonymizer is implemented by detectors that can replace values with
+// realistic fake values instead of tokens. The mapping keeps them reversible.
+type Pseudonymizer interface {
+	// Pseudonym returns the n-th fake value (n starts at 1) for original,
+	// or "" if there is none and a token should be used instead.
+	Pseudonym(original string, n int) string
+}
+
+// detectorModes resolves the masking mode of every detector
+func detectorModes(cfg Config, detectors []Detector) (map[string]string, error) {
+	modes := make(map[string]string, len(detectors))
+	for _, d := range detectors {
+		mode := detectorMode(cfg, d.Name())
+		field := "masking_mode"
+		if cfg.Detectors[d.Name()].Mode != "" {
+			field = "mode"
+		}
+		switch mode {
+		case ModeToken, ModePseudonym:
+		case ModePrefixPreserving:
+			if prefixPreservingDetectors[d.Name()] {
+				break
+			}
+			if field == "mode" {
+				return nil, &ConfigError{Detector: d.Name(), Field: field, Value: mode, Err: errUnsupportedMode}
+			}
+			mode = ModeToken
+		default:
+			ce := &ConfigError{Field: field, Value: mode, Err: errUnknownMode}
+			if field == "mode" {
+				ce.Detector = d.Name()
+			}
+			return nil, ce
+		}
+		modes[d.Name()] = mode
+	}
+	return modes, nil
+}
+
+// detectorMode returns the configured mode of one detector, without validating it
+func detectorMode(cfg Config, name string) string {
+	if mode := cfg.Detectors[name].Mode; mode != "" {
+		return mode
+	}
+	if cfg.MaskingMode != "" {
+		return cfg.MaskingMode
+	}
+	return ModeToken
+}
+
+// documentationIPv4 are the RFC 5737 TEST-NET ranges
+var documentationIPv4 = []string{"192.0.2.", "198.51.100.", "203.0.113."}
+
+// pseudonymIPv4 returns the n-th address of the RFC 5737 documentation ranges
+// (192.0.2.1 ... 203.0.113.254), or "" once they are used up.
+func pseudonymIPv4(n int) string {
+	i := n - 1
+	if i < 0 || i >= len(documentationIPv4)*254 {
+		return ""
+	}
+	return fmt.Sprintf("%s%d", documentationIPv4[i/254], i%254+1)
+}
+
+// pseudonymIPv6 returns the n-th address of the RFC 3849 documentation prefix 2001:db8::/32
+func pseudonymIPv6(n int) string {
+	if n < 1 {
+		return ""
+	}
+	var b [16]byte
+	copy(b[:], []byte{0x20, 0x01, 0x0d, 0xb8})
+	for i := 15; i >= 12; i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+	return netip.AddrFrom16(b).String()
+}
+
+const (
+	consonants = "bcdfghjklmnprstvz"
+	vowels     = "aeiou"
+)
+
+// pseudonymName returns a fake name with the same shape as original:
+// letters become pronounceable letters of the same case, digits become digits
+// and punctuation ("-", ".", "_") is kept, so "xy-db-prod-01" may become "ka-mo-riva-37".
+func pseudonymName(original string, n int) string {
+	rng := rand.New(rand.NewPCG(uint64(n), uint64(len(original))))
+	var b strings.Builder
+	vowel := false
+	for _, r := range original {
+		switch {
+		case unicode.IsLetter(r):
+			set := consonants
+			if vowel {
+				set = vowels
+			}
+			c := rune(set[rng.IntN(len(set))])
+			if unicode.IsUpper(r) {
+				c = unicode.ToUpper(c)
+			}
+			b.WriteRune(c)
+			vowel = !vowel
+		case unicode.IsDigit(r):
+			b.WriteByte(byte('0' + rng.IntN(10)))
+		default:
+			b.WriteRune(r)
+			vowel = false
+		}
+	}
+	return b.String()
+}
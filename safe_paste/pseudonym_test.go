@@ -0,0 +1,104 @@
+package safe_paste
+
+import (
+	"errors"
+	"net/netip"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestPseudonymIPv4(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "192.0.2.1"},
+		{254, "192.0.2.254"},
+		{255, "198.51.100.1"},
+		{762, "203.0.113.254"},
+		{763, ""},
+	}
+	for _, tt := range tests {
+		if got := pseudonymIPv4(tt.n); got != tt.want {
+			t.Errorf("pseudonymIPv4(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPseudonymIPv6(t *testing.T) {
+	doc := netip.MustParsePrefix("2001:db8::/32")
+	for _, n := range []int{1, 2, 300, 70000} {
+		addr, err := netip.ParseAddr(pseudonymIPv6(n))
+		if err != nil || !doc.Contains(addr) {
+			t.Errorf("pseudonymIPv6(%d) = %v, want an address in %v", n, addr, doc)
+		}
+	}
+}
+
+func TestPseudonymNameShape(t *testing.T) {
+	shape := func(s string) string {
+		return regexp.MustCompile(`[0-9]`).ReplaceAllString(
+			regexp.MustCompile(`[A-Z]`).ReplaceAllString(
+				regexp.MustCompile(`[a-z]`).ReplaceAllString(s, "a"), "A"), "9")
+	}
+	for _, original := range []string{"xy-db-prod-01", "XY123abc456prd", "xy-auth.prod.example.com"} {
+		fake := pseudonymName(original, 1)
+		if fake == original || shape(fake) != shape(original) {
+			t.Errorf("pseudonymName(%q) = %q, want a different value of the same shape", original, fake)
+		}
+		if fake != pseudonymName(original, 1) {
+			t.Errorf("pseudonymName(%q) is not deterministic", original)
+		}
+	}
+}
+
+func TestMaskPseudonymMode(t *testing.T) {
+	m, err := NewMasker(Config{
+		HostnamePattern: `\bxy-[a-z0-9.-]+\b`,
+		Keywords:        []string{"acme"},
+		MaskingMode:     ModePseudonym,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "acme: xy-db-01 at 192.168.1.50 and 2001:0db8:85a3:0000:0000:8a2e:0370:7334, again 192.168.1.50"
+	result := m.Mask(input)
+
+	if !strings.Contains(result.MaskedText, "192.0.2.1 and 2001:db8::1, again 192.0.2.1") {
+		t.Errorf("MaskedText = %q, want documentation addresses", result.MaskedText)
+	}
+	if !strings.HasPrefix(result.MaskedText, "kw1: ") {
+		t.Errorf("MaskedText = %q, want keywords as tokens", result.MaskedText)
+	}
+	if strings.Contains(result.MaskedText, "xy-db-01") {
+		t.Errorf("MaskedText = %q, hostname not masked", result.MaskedText)
+	}
+	if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+		t.Errorf("UnmaskText = %q, want %q", got, input)
+	}
+}
+
+func TestMaskPseudonymSkipsValuesInInput(t *testing.T) {
+	m, err := NewMasker(Config{MaskingMode: ModePseudonym})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "real 192.0.2.1 and 10.0.0.1"
+	result := m.Mask(input)
+	if result.MaskedText != "real 192.0.2.2 and 192.0.2.3" {
+		t.Errorf("MaskedText = %q", result.MaskedText)
+	}
+}
+
+func TestMaskingModeErrors(t *testing.T) {
+	_, err := NewMasker(Config{MaskingMode: "scramble"})
+	if !errors.Is(err, errUnknownMode) {
+		t.Errorf("NewMasker error = %v, want errUnknownMode", err)
+	}
+	_, err = NewMasker(Config{Detectors: map[string]DetectorConfig{DetectorIPv4: {Mode: "scramble"}}})
+	var ce *ConfigError
+	if !errors.As(err, &ce) || ce.Detector != DetectorIPv4 || ce.Field != "mode" {
+		t.Errorf("NewMasker error = %v, want ipv4 mode ConfigError", err)
+	}
+}
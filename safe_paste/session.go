@@ -0,0 +1,203 @@
+package safe_paste
+
+import (
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSessionTTL is how long sessions are kept when Config.SessionTTLHours is 0
+const DefaultSessionTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidSessionName = errors.New("invalid session name")
+	ErrSessionNotFound    = errors.New("session not found")
+
+	sessionNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]{0,63}$`)
+)
+
+// Session is a named mapping kept on disk so a reply can be unmasked later
+type Session struct {
+	Name    string            `json:"name"`
+	Created time.Time         `json:"created"`
+	Updated time.Time         `json:"updated"`
+	Mapping map[string]string `json:"mapping"` // masked -> original
+}
+
+// SessionStore keeps each session as a file in one directory.
+// Plain stores write JSON; stores opened through a Vault encrypt every file.
+type SessionStore struct {
+	dir  string
+	ext  string
+	aead cipher.AEAD // nil for plain JSON files
+}
+
+// DefaultSessionDir is the "sessions" directory next to config.json (portable)
+func DefaultSessionDir() string {
+	return filepath.Join(filepath.Dir(ConfigPath()), "sessions")
+}
+
+// NewSessionStore returns a store writing plain JSON files.
+// Use OpenVault to keep sessions encrypted.
+func NewSessionStore(dir string) *SessionStore {
+	return &SessionStore{dir: dir, ext: ".json"}
+}
+
+// NewSessionName returns a timestamp based name, e.g. "session-20250101-150405"
+func NewSessionName(now time.Time) string {
+	return "session-" + now.Format("20060102-150405")
+}
+
+// UniqueSessionName returns name, or name with "-2", "-3"... appended if one of
+// sessions has it already, so a new mask never replaces an older mapping.
+// Names are compared case-insensitively, as on Windows file systems.
+func UniqueSessionName(name string, sessions []Session) string {
+	taken := make(map[string]bool, len(sessions))
+	for _, sess := range sessions {
+		taken[strings.ToLower(sess.Name)] = true
+	}
+	unique := name
+	for n := 2; taken[strings.ToLower(unique)]; n++ {
+		unique = name + "-" + strconv.Itoa(n)
+	}
+	return unique
+}
+
+// SessionTTL returns how long sessions are kept; zero means they never expire
+func (cfg Config) SessionTTL() time.Duration {
+	switch {
+	case cfg.SessionTTLHours < 0:
+		return 0
+	case cfg.SessionTTLHours == 0:
+		return DefaultSessionTTL
+	}
+	return time.Duration(cfg.SessionTTLHours) * time.Hour
+}
+
+func (s *SessionStore) path(name string) (string, error) {
+	if !sessionNameRegex.MatchString(name) || strings.Contains(name, "..") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSessionName, name)
+	}
+	return filepath.Join(s.dir, name+s.ext), nil
+}
+
+// Save writes the session, setting Created and Updated timestamps
+func (s *SessionStore) Save(sess Session) error {
+	path, err := s.path(sess.Name)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if sess.Created.IsZero() {
+		sess.Created = now
+	}
+	sess.Updated = now
+	data, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return err
+	}
+	if s.aead != nil {
+		data, err = encrypt(s.aead, data, sess.Name)
+		if err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (s *SessionStore) Load(name string) (Session, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return Session{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Session{}, fmt.Errorf("%w: %q", ErrSessionNotFound, name)
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	if s.aead != nil {
+		if data, err = decrypt(s.aead, data, name); err != nil {
+			return Session{}, fmt.Errorf("session %q: %w", name, err)
+		}
+	}
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return Session{}, fmt.Errorf("session %q: %w", name, err)
+	}
+	return sess, nil
+}
+
+// List returns all readable sessions, most recently updated first
+func (s *SessionStore) List() ([]Session, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []Session
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), s.ext)
+		if e.IsDir() || !ok {
+			continue
+		}
+		sess, err := s.Load(name)
+		if err != nil {
+			continue // skip foreign or corrupted files
+		}
+		sessions = append(sessions, sess)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
+
+func (s *SessionStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %q", ErrSessionNotFound, name)
+	}
+	return err
+}
+
+// Prune deletes sessions not updated within ttl and returns how many were removed.
+// A zero ttl keeps everything.
+func (s *SessionStore) Prune(ttl time.Duration) (int, error) {
+	if ttl <= 0 {
+		return 0, nil
+	}
+	sessions, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	cutoff := time.Now().Add(-ttl)
+	for _, sess := range sessions {
+		if sess.Updated.Before(cutoff) {
+			if err := s.Delete(sess.Name); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
@@ -0,0 +1,104 @@
+package safe_paste
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionStoreRoundTrip(t *testing.T) {
+	store := NewSessionStore(t.TempDir())
+	mapping := map[string]string{"ip1": "192.168.1.100", "hostname1": "xy-server"}
+
+	if err := store.Save(Session{Name: "incident 42", Mapping: mapping}); err != nil {
+		t.Fatal(err)
+	}
+	sess, err := store.Load("incident 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess.Created.IsZero() || sess.Updated.IsZero() {
+		t.Errorf("timestamps not set: %+v", sess)
+	}
+	for masked, original := range mapping {
+		if sess.Mapping[masked] != original {
+			t.Errorf("Mapping[%v] = %v, want %v", masked, sess.Mapping[masked], original)
+		}
+	}
+
+	sessions, err := store.List()
+	if err != nil || len(sessions) != 1 || sessions[0].Name != "incident 42" {
+		t.Fatalf("List = %+v, %v", sessions, err)
+	}
+
+	if err := store.Delete("incident 42"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("incident 42"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Load after Delete error = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestSessionStoreInvalidNames(t *testing.T) {
+	store := NewSessionStore(t.TempDir())
+	for _, name := range []string{"", "../config", "a/b", `a\b`, ".hidden", "x..y"} {
+		if err := store.Save(Session{Name: name}); !errors.Is(err, ErrInvalidSessionName) {
+			t.Errorf("Save(%q) error = %v, want ErrInvalidSessionName", name, err)
+		}
+	}
+}
+
+func TestSessionStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	store := NewSessionStore(dir)
+	if err := store.Save(Session{Name: "fresh"}); err != nil {
+		t.Fatal(err)
+	}
+	old := `{"name":"old","created":"2020-01-01T00:00:00Z","updated":"2020-01-01T00:00:00Z","mapping":{}}`
+	if err := os.WriteFile(filepath.Join(dir, "old.json"), []byte(old), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := store.Prune(24 * time.Hour)
+	if err != nil || removed != 1 {
+		t.Fatalf("Prune = %d, %v, want 1", removed, err)
+	}
+	sessions, _ := store.List()
+	if len(sessions) != 1 || sessions[0].Name != "fresh" {
+		t.Errorf("sessions after Prune = %+v", sessions)
+	}
+}
+
+func TestUniqueSessionName(t *testing.T) {
+	sessions := []Session{{Name: "incident 42"}, {Name: "Incident 42-2"}, {Name: "session-20250101-150405"}}
+	tests := []struct {
+		name, want string
+	}{
+		{"incident 43", "incident 43"},
+		{"incident 42", "incident 42-3"},
+		{"session-20250101-150405", "session-20250101-150405-2"},
+	}
+	for _, tt := range tests {
+		if got := UniqueSessionName(tt.name, sessions); got != tt.want {
+			t.Errorf("UniqueSessionName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSessionTTL(t *testing.T) {
+	tests := []struct {
+		hours int
+		want  time.Duration
+	}{
+		{0, DefaultSessionTTL},
+		{-1, 0},
+		{12, 12 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := (Config{SessionTTLHours: tt.hours}).SessionTTL(); got != tt.want {
+			t.Errorf("SessionTTL(%d) = %v, want %v", tt.hours, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package safe_paste
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Token format presets for Config.TokenFormat
+var tokenPresets = map[string]string{
+	"plain":      "{type}{n}",         // ip1, hostname1
+	"brackets":   "⟦{TYPE}_{n}⟧",      // ⟦IP_1⟧
+	"angle":      "<<{TYPE}_{n}>>",    // <<HOSTNAME_3>>
+	"underscore": "__SP_{TYPE}_{n}__", // __SP_IP_1__
+}
+
+const defaultTokenFormat = "plain"
+
+// Token id modes for Config.TokenIDs
+const (
+	TokenIDsSequential = "sequential" // ip1, ip2... by order of appearance
+	TokenIDsKeyed      = "keyed"      // ip_3fa81c: HMAC of the value, stable across sessions
+)
+
+// keyedIDLength is the number of hex digits of a keyed id before collisions extend it
+const keyedIDLength = 6
+
+// TokenScheme renders and recognizes tokens built from a template with
+// a {type} or {TYPE} placeholder followed by an {n} placeholder.
+type TokenScheme struct {
+	before, between, after string // literal text around the placeholders
+	upper                  bool   // {TYPE}: prefix is written in upper case
+	keyed                  bool   // ids are hex digests instead of numbers
+	re                     *regexp.Regexp
+	fuzzy                  *regexp.Regexp // prefix + optional separator + id, any case
+}
+
+var (
+	errTokenTemplate = errors.New("template needs {type} or {TYPE} followed by {n}")
+	errKeyedTemplate = errors.New("keyed token ids need a separator between {type} and {n}")
+)
+
+// NewTokenScheme accepts a preset name ("plain", "brackets", "angle", "underscore")
+// or a template such as "[[{TYPE}-{n}]]". An empty format means "plain".
+func NewTokenScheme(format string) (*TokenScheme, error) {
+	return newTokenScheme(format, false)
+}
+
+// NewKeyedTokenScheme is like NewTokenScheme for hex ids such as "ip_3fa81c".
+// The plain preset becomes "{type}_{n}", other templates need a separator.
+func NewKeyedTokenScheme(format string) (*TokenScheme, error) {
+	return newTokenScheme(format, true)
+}
+
+func newTokenScheme(format string, keyed bool) (*TokenScheme, error) {
+	if format == "" {
+		format = defaultTokenFormat
+	}
+	template := format
+	if preset, ok := tokenPresets[format]; ok {
+		template = preset
+	}
+	if keyed && format == defaultTokenFormat {
+		template = "{type}_{n}"
+	}
+
+	s := &TokenScheme{keyed: keyed}
+	typeAt := strings.Index(template, "{type}")
+	if upperAt := strings.Index(template, "{TYPE}"); upperAt >= 0 {
+		if typeAt >= 0 {
+			return nil, &ConfigError{Field: "token_format", Value: format, Err: errTokenTemplate}
+		}
+		typeAt, s.upper = upperAt, true
+	}
+	nAt := strings.Index(template, "{n}")
+	if typeAt < 0 || nAt < typeAt+len("{type}") {
+		return nil, &ConfigError{Field: "token_format", Value: format, Err: errTokenTemplate}
+	}
+	s.before = template[:typeAt]
+	s.between = template[typeAt+len("{type}") : nAt]
+	s.after = template[nAt+len("{n}"):]
+	for _, placeholder := range []string{"{type}", "{TYPE}", "{n}"} {
+		if strings.Contains(s.before+s.between+s.after, placeholder) {
+			return nil, &ConfigError{Field: "token_format", Value: format, Err: errTokenTemplate}
+		}
+	}
+	// Hex ids would run into the prefix, "ipab12" could be ("ipab", "12")
+	if keyed && s.between == "" {
+		return nil, &ConfigError{Field: "token_format", Value: format, Err: errKeyedTemplate}
+	}
+
+	// Prefixes are letters only, so "ip12" parses as ("ip", "12")
+	typeRe := `[a-z]+`
+	if s.upper {
+		typeRe = `[A-Z]+`
+	}
+	idRe := `[0-9]+`
+	if keyed {
+		idRe = `[0-9a-f]{` + strconv.Itoa(keyedIDLength) + `,}`
+	}
+	s.re = regexp.MustCompile(regexp.QuoteMeta(s.before) + `(` + typeRe + `)` +
+		regexp.QuoteMeta(s.between) + `(` + idRe + `)` + regexp.QuoteMeta(s.after))
+	// No space: "ip 1" or "user 1" in prose is not a token
+	s.fuzzy = regexp.MustCompile(`(?i)([a-z]+)([_-]?)(` + idRe + `)`)
+	return s, nil
+}
+
+var (
+	errUnknownTokenIDs = errors.New(`must be "sequential" or "keyed"`)
+	errNoTokenSecret   = errors.New("keyed token ids need a token_secret")
+)
+
+// tokenSchemeFor builds the token scheme and HMAC key of a config
+func tokenSchemeFor(cfg Config) (*TokenScheme, []byte, error) {
+	switch cfg.TokenIDs {
+	case "", TokenIDsSequential:
+		scheme, err := NewTokenScheme(cfg.TokenFormat)
+		return scheme, nil, err
+	case TokenIDsKeyed:
+		if cfg.TokenSecret == "" {
+			return nil, nil, &ConfigError{Field: "token_secret", Err: errNoTokenSecret}
+		}
+		scheme, err := NewKeyedTokenScheme(cfg.TokenFormat)
+		return scheme, []byte(cfg.TokenSecret), err
+	default:
+		return nil, nil, &ConfigError{Field: "token_ids", Value: cfg.TokenIDs, Err: errUnknownTokenIDs}
+	}
+}
+
+// Format builds the token for a detector prefix and id, e.g. ("ip", "1") -> "ip1"
+func (s *TokenScheme) Format(prefix, id string) string {
+	if s.upper {
+		prefix = strings.ToUpper(prefix)
+	}
+	return s.before + prefix + s.between + id + s.after
+}
+
+// Keyed reports whether ids are keyed hex digests rather than numbers
+func (s *TokenScheme) Keyed() bool {
+	return s.keyed
+}
+
+// Parse splits a token into its prefix and id
+func (s *TokenScheme) Parse(token string) (prefix, id string, ok bool) {
+	m := s.re.FindStringSubmatch(token)
+	if m == nil || len(m[0]) != len(token) {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), m[2], true
+}
+
+// FindAll returns the offsets of every token-shaped string in text,
+// whether it is in a mapping or not.
+func (s *TokenScheme) FindAll(text string) [][]int {
+	var matches [][]int
+	for _, m := range s.re.FindAllStringIndex(text, -1) {
+		if atTokenBoundary(text, m[0], m[1]) {
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
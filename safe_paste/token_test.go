@@ -0,0 +1,161 @@
+package safe_paste
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokenSchemePresets(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "hostname12"},
+		{"plain", "hostname12"},
+		{"brackets", "⟦HOSTNAME_12⟧"},
+		{"angle", "<<HOSTNAME_12>>"},
+		{"underscore", "__SP_HOSTNAME_12__"},
+		{"[{type}:{n}]", "[hostname:12]"},
+	}
+	for _, tt := range tests {
+		s, err := NewTokenScheme(tt.format)
+		if err != nil {
+			t.Fatalf("NewTokenScheme(%q) error = %v", tt.format, err)
+		}
+		token := s.Format("hostname", "12")
+		if token != tt.want {
+			t.Errorf("Format with %q = %q, want %q", tt.format, token, tt.want)
+		}
+		prefix, id, ok := s.Parse(token)
+		if !ok || prefix != "hostname" || id != "12" {
+			t.Errorf("Parse(%q) = %q, %q, %v", token, prefix, id, ok)
+		}
+	}
+}
+
+func TestTokenSchemeInvalid(t *testing.T) {
+	for _, format := range []string{"{n}", "{type}", "{n}{type}", "{type}{TYPE}{n}", "{type}{n}{n}"} {
+		_, err := NewTokenScheme(format)
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Field != "token_format" {
+			t.Errorf("NewTokenScheme(%q) error = %v, want token_format ConfigError", format, err)
+		}
+	}
+}
+
+func TestMaskRenumbersTokenLookalikes(t *testing.T) {
+	m, err := NewMasker(Config{HostnamePattern: `\bxy-[a-z0-9.-]+\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "interface ip1 has 10.0.0.1, ip2 has 10.0.0.2"
+	result := m.Mask(input)
+	expected := "interface ip1 has ip3, ip2 has ip4"
+	if result.MaskedText != expected {
+		t.Fatalf("MaskedText = %q, want %q", result.MaskedText, expected)
+	}
+	if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+		t.Errorf("UnmaskText = %q, want %q", got, input)
+	}
+}
+
+func TestMaskRenumbersFuzzyLookalikes(t *testing.T) {
+	m, err := NewMasker(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{"interface IP1 is on 10.0.0.1", "interface IP1 is on ip2"},
+		{"ip_1 and ip-2 are 10.0.0.1 and 10.0.0.2", "ip_1 and ip-2 are ip3 and ip4"},
+		{"range ip1-2 on 10.0.0.1", "range ip1-2 on ip2"},
+		{"step ip 1: ping 10.0.0.1", "step ip 1: ping ip1"},
+	}
+	for _, tt := range tests {
+		result := m.Mask(tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) = %q, want %q", tt.input, result.MaskedText, tt.masked)
+		}
+		if got, _ := UnmaskTextWithReport(result.MaskedText, result.Mapping, UnmaskOptions{Fuzzy: true}); got != tt.input {
+			t.Errorf("fuzzy round trip of %q = %q", tt.input, got)
+		}
+	}
+}
+
+func TestMaskWithBracketTokens(t *testing.T) {
+	m, err := NewMasker(Config{HostnamePattern: `\bxy-[a-z0-9.-]+\b`, TokenFormat: "brackets"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := "Check xy-backend at 192.168.1.50"
+	result := m.Mask(input)
+	expected := "Check ⟦HOSTNAME_1⟧ at ⟦IP_1⟧"
+	if result.MaskedText != expected {
+		t.Fatalf("MaskedText = %q, want %q", result.MaskedText, expected)
+	}
+	if got := UnmaskText(result.MaskedText+"⟦IP_1⟧s", result.Mapping); got != input+"192.168.1.50s" {
+		t.Errorf("UnmaskText = %q", got)
+	}
+}
+
+func TestMaskKeyedTokens(t *testing.T) {
+	cfg := Config{HostnamePattern: `\bxy-[a-z0-9.-]+\b`, TokenIDs: TokenIDsKeyed, TokenSecret: "team secret"}
+	m, err := NewMasker(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := m.Mask("xy-db-01 at 10.0.0.1")
+	second := m.Mask("10.0.0.2 talks to 10.0.0.1 and xy-db-01")
+	tokenOf := func(r MaskResult, original string) string {
+		for token, value := range r.Mapping {
+			if value == original {
+				return token
+			}
+		}
+		return ""
+	}
+	for _, original := range []string{"xy-db-01", "10.0.0.1"} {
+		a, b := tokenOf(first, original), tokenOf(second, original)
+		if a == "" || a != b {
+			t.Errorf("%s masked as %q and %q, want the same token", original, a, b)
+		}
+		if prefix, id, ok := m.TokenScheme().Parse(a); !ok || len(id) != keyedIDLength || !strings.HasPrefix(a, prefix+"_") {
+			t.Errorf("token %q does not parse as a keyed token", a)
+		}
+	}
+	if tokenOf(second, "10.0.0.1") == tokenOf(second, "10.0.0.2") {
+		t.Error("different values got the same token")
+	}
+
+	other, _ := NewMasker(Config{TokenIDs: TokenIDsKeyed, TokenSecret: "other secret"})
+	if got := other.Mask("10.0.0.1").MaskedText; got == tokenOf(first, "10.0.0.1") {
+		t.Errorf("another secret gave the same token %q", got)
+	}
+
+	masked := strings.ToUpper(second.MaskedText)
+	restored, _ := UnmaskTextWithReport(masked, second.Mapping, UnmaskOptions{Fuzzy: true, Scheme: m.TokenScheme()})
+	if want := "10.0.0.2 TALKS TO 10.0.0.1 AND xy-db-01"; restored != want {
+		t.Errorf("fuzzy unmask = %q, want %q", restored, want)
+	}
+}
+
+func TestKeyedTokenConfigErrors(t *testing.T) {
+	tests := []struct {
+		cfg   Config
+		field string
+	}{
+		{Config{TokenIDs: TokenIDsKeyed}, "token_secret"},
+		{Config{TokenIDs: "random", TokenSecret: "s"}, "token_ids"},
+		{Config{TokenIDs: TokenIDsKeyed, TokenSecret: "s", TokenFormat: "<{type}{n}>"}, "token_format"},
+	}
+	for _, tt := range tests {
+		_, err := NewMasker(tt.cfg)
+		var ce *ConfigError
+		if !errors.As(err, &ce) || ce.Field != tt.field {
+			t.Errorf("NewMasker(%+v) error = %v, want a %s ConfigError", tt.cfg, err, tt.field)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package safe_paste
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// UnmaskText replaces masked values (ip1, hostname1, kw1) with original values.
+// Only whole tokens are replaced, so ip1 is never replaced inside ip10 or xip1,
+// and the result does not depend on map iteration order.
+func UnmaskText(maskedText string, mapping map[string]string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range findTokens(maskedText, mapping) {
+		out.WriteString(maskedText[last:m[0]])
+		out.WriteString(mapping[maskedText[m[0]:m[1]]])
+		last = m[1]
+	}
+	out.WriteString(maskedText[last:])
+	return out.String()
+}
+
+// findTokens returns the [start, end) offsets of every mapped token in text,
+// preferring the longest token and skipping tokens glued to other word characters.
+func findTokens(text string, mapping map[string]string) [][]int {
+	if len(mapping) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(mapping))
+	for masked := range mapping {
+		if masked != "" {
+			keys = append(keys, regexp.QuoteMeta(masked))
+		}
+	}
+	// Longest first, so ip10 wins over ip1 at the same position
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	re := regexp.MustCompile(strings.Join(keys, "|"))
+
+	var matches [][]int
+	for offset := 0; offset < len(text); {
+		loc := re.FindStringIndex(text[offset:])
+		if loc == nil {
+			break
+		}
+		start, end := offset+loc[0], offset+loc[1]
+		if atTokenBoundary(text, start, end) {
+			matches = append(matches, []int{start, end})
+			offset = end
+		} else {
+			offset = start + 1
+		}
+	}
+	return matches
+}
+
+// atTokenBoundary reports whether text[start:end] is not glued to surrounding word characters
+func atTokenBoundary(text string, start, end int) bool {
+	if start > 0 && isWordByte(text[start-1]) && isWordByte(text[start]) {
+		return false
+	}
+	if end < len(text) && isWordByte(text[end]) && isWordByte(text[end-1]) {
+		return false
+	}
+	return true
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// UnmaskOptions controls UnmaskTextWithReport
+type UnmaskOptions struct {
+	// Fuzzy also restores tokens rewritten by an AI, such as IP1, ip_1 or
+	// hostname1s. Every such match is listed in the report.
+	Fuzzy bool
+	// Scheme is the token format used when masking; nil means "plain"
+	Scheme *TokenScheme
+	// Domains are the internal domains kept after masked labels, see
+	// Masker.KeptDomains. A token followed by one is not an added domain.
+	Domains []string
+}
+
+// FuzzyMatch is a token restored in fuzzy mode, listed so the user can review it
+type FuzzyMatch struct {
+	Found    string   // text as it appeared, e.g. "IP_1"
+	Token    string   // mapped token, e.g. "ip1"
+	Restored string   // text written instead of Found
+	Offset   int      // byte offset of Found in the masked text
+	Reasons  []string // "case", "separator", "delimiters", "plural", "suffix"
+}
+
+// UnmaskReport describes what UnmaskTextWithReport did
+type UnmaskReport struct {
+	Restored []string     // mapped tokens found in the text
+	Unknown  []string     // token-shaped text with no mapping, e.g. invented by the AI
+	Missing  []string     // mapped tokens that never appear in the text
+	Fuzzy    []FuzzyMatch // tokens restored in fuzzy mode
+}
+
+// Warnings returns one human readable line per problem in the report
+func (r UnmaskReport) Warnings() []string {
+	var warnings []string
+	for _, token := range r.Unknown {
+		warnings = append(warnings, fmt.Sprintf("Unknown token %s (not in the mapping, left as is)", token))
+	}
+	if len(r.Missing) > 0 {
+		warnings = append(warnings, fmt.Sprintf("Not in the response: %s", strings.Join(r.Missing, ", ")))
+	}
+	for _, f := range r.Fuzzy {
+		warnings = append(warnings, fmt.Sprintf("Fuzzy match %q -> %q (%s)", f.Found, f.Restored, strings.Join(f.Reasons, ", ")))
+	}
+	return warnings
+}
+
+// UnmaskTextWithReport is UnmaskText with an optional fuzzy mode and a report
+func UnmaskTextWithReport(maskedText string, mapping map[string]string, opts UnmaskOptions) (string, UnmaskReport) {
+	var report UnmaskReport
+	type replacement struct {
+		start, end int
+		value      string
+	}
+	var replacements []replacement
+	taken := func(start, end int) bool {
+		for _, r := range replacements {
+			if start < r.end && r.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	scheme := opts.Scheme
+	if scheme == nil {
+		scheme, _ = NewTokenScheme(defaultTokenFormat)
+	}
+	seen := make(map[string]bool)
+	if opts.Fuzzy {
+		for _, f := range findFuzzyTokens(maskedText, mapping, scheme, opts.Domains) {
+			replacements = append(replacements, replacement{f.Offset, f.Offset + len(f.Found), f.Restored})
+			seen[f.Token] = true
+			if len(f.Reasons) > 0 {
+				report.Fuzzy = append(report.Fuzzy, f)
+			}
+		}
+	}
+	for _, m := range findTokens(maskedText, mapping) {
+		token := maskedText[m[0]:m[1]]
+		seen[token] = true
+		if !taken(m[0], m[1]) {
+			replacements = append(replacements, replacement{m[0], m[1], mapping[token]})
+		}
+	}
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start < replacements[j].start })
+
+	for token := range mapping {
+		if seen[token] {
+			report.Restored = append(report.Restored, token)
+		} else {
+			report.Missing = append(report.Missing, token)
+		}
+	}
+	report.Unknown = findUnknownTokens(maskedText, mapping, scheme)
+	sortTokens(report.Restored, scheme)
+	sortTokens(report.Missing, scheme)
+
+	var out strings.Builder
+	last := 0
+	for _, r := range replacements {
+		out.WriteString(maskedText[last:r.start])
+		out.WriteString(r.value)
+		last = r.end
+	}
+	out.WriteString(maskedText[last:])
+	return out.String(), report
+}
+
+// findFuzzyTokens finds mapped tokens of the scheme, including mangled forms:
+// other case, a separator between prefix and id, missing delimiters, or a
+// plural "s" glued to the end. Exact tokens are returned with no Reasons.
+func findFuzzyTokens(text string, mapping map[string]string, scheme *TokenScheme, domains []string) []FuzzyMatch {
+	byID := make(map[string]string) // "prefix/id" -> token
+	for token := range mapping {
+		if prefix, id, ok := scheme.Parse(token); ok {
+			byID[prefix+"/"+id] = token
+		}
+	}
+	if len(byID) == 0 {
+		return nil
+	}
+
+	var matches []FuzzyMatch
+	for _, loc := range scheme.fuzzy.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[0], loc[1]
+		prefix, sep, id := text[loc[2]:loc[3]], text[loc[4]:loc[5]], text[loc[6]:loc[7]]
+		token, ok := byID[strings.ToLower(prefix)+"/"+strings.ToLower(id)]
+		if !ok {
+			continue
+		}
+
+		var reasons []string
+		// Delimiters of the scheme are optional, e.g. "IP_1" for "⟦IP_1⟧"
+		hasBefore := scheme.before != "" && strings.HasSuffix(text[:start], scheme.before)
+		hasAfter := scheme.after != "" && strings.HasPrefix(text[end:], scheme.after)
+		if hasBefore {
+			start -= len(scheme.before)
+		}
+		if hasAfter {
+			end += len(scheme.after)
+		}
+		if scheme.before != "" && !hasBefore || scheme.after != "" && !hasAfter {
+			reasons = append(reasons, "delimiters")
+		}
+		if start > 0 && isWordByte(text[start]) && isWordByte(text[start-1]) {
+			continue // glued to another word, e.g. "xip1"
+		}
+		plural := ""
+		if !hasAfter && end < len(text) && (text[end] == 's' || text[end] == 'S') && !continuesWord(text, end+1) {
+			plural = text[end : end+1]
+		}
+		if plural == "" && isWordByte(text[end-1]) && continuesWord(text, end) {
+			continue // glued to another word, e.g. "ip1x"
+		}
+
+		wantPrefix := strings.ToLower(prefix)
+		if scheme.upper {
+			wantPrefix = strings.ToUpper(prefix)
+		}
+		if prefix != wantPrefix || id != strings.ToLower(id) {
+			reasons = append(reasons, "case")
+		}
+		if sep != scheme.between {
+			reasons = append(reasons, "separator")
+		}
+		if plural != "" {
+			reasons = append(reasons, "plural")
+		}
+		// e.g. "hostname1.example.com": the AI added a domain to the restored value.
+		// "hostname1.corp.example.com" keeps the internal domain that was masked around.
+		if after := end + len(plural); after+1 < len(text) && text[after] == '.' && isWordByte(text[after+1]) && !keptDomain(text[after+1:], domains) {
+			reasons = append(reasons, "suffix")
+		}
+		matches = append(matches, FuzzyMatch{
+			Found:    text[start : end+len(plural)],
+			Token:    token,
+			Restored: mapping[token] + plural,
+			Offset:   start,
+			Reasons:  reasons,
+		})
+	}
+	return matches
+}
+
+// keptDomain reports whether the dotted name at the start of rest ends with one of
+// domains, e.g. "label2.corp.example.com" after "label1." in the labels mode
+func keptDomain(rest string, domains []string) bool {
+	end := 0
+	for end < len(rest) && (isWordByte(rest[end]) || rest[end] == '-' || rest[end] == '.') {
+		end++
+	}
+	name := strings.ToLower(strings.TrimRight(rest[:end], "."))
+	for _, d := range domains {
+		if name == d || strings.HasSuffix(name, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// findUnknownTokens returns token-shaped strings that are not in the mapping.
+// With the plain format only prefixes used by the mapping count, so words like
+// "utf8" are not reported.
+func findUnknownTokens(text string, mapping map[string]string, scheme *TokenScheme) []string {
+	prefixes := make(map[string]bool)
+	for token := range mapping {
+		if prefix, _, ok := scheme.Parse(token); ok {
+			prefixes[prefix] = true
+		}
+	}
+	delimited := scheme.before != "" || scheme.after != ""
+
+	var unknown []string
+	reported := make(map[string]bool)
+	for _, loc := range scheme.FindAll(text) {
+		token := text[loc[0]:loc[1]]
+		if _, ok := mapping[token]; ok || reported[token] {
+			continue
+		}
+		prefix, _, _ := scheme.Parse(token)
+		if delimited || prefixes[prefix] {
+			unknown = append(unknown, token)
+			reported[token] = true
+		}
+	}
+	return unknown
+}
+
+// sortTokens orders tokens by prefix, then numerically by id (ip2 before ip10)
+func sortTokens(tokens []string, scheme *TokenScheme) {
+	sort.Slice(tokens, func(i, j int) bool {
+		pi, idi, oki := scheme.Parse(tokens[i])
+		pj, idj, okj := scheme.Parse(tokens[j])
+		if !oki || !okj || pi != pj {
+			return tokens[i] < tokens[j]
+		}
+		if len(idi) != len(idj) {
+			return len(idi) < len(idj)
+		}
+		return idi < idj
+	})
+}
+
+// continuesWord reports whether a word character follows position i
+func continuesWord(text string, i int) bool {
+	return i < len(text) && isWordByte(text[i])
+}
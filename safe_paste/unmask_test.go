@@ -0,0 +1,169 @@
+package safe_paste
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnmaskTextTokenBoundaries(t *testing.T) {
+	mapping := map[string]string{
+		"ip1":  "10.0.0.1",
+		"ip10": "10.0.0.10",
+		"ip11": "10.0.0.11",
+		"kw1":  "acme",
+	}
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"ip1 ip10 ip11", "10.0.0.1 10.0.0.10 10.0.0.11"},
+		{"ip10,ip1.", "10.0.0.10,10.0.0.1."},
+		{"ip12 is unknown", "ip12 is unknown"},
+		{"xip1 and ip1x stay", "xip1 and ip1x stay"},
+		{"(kw1) `ip1`", "(acme) `10.0.0.1`"},
+		{"ip1:8080", "10.0.0.1:8080"},
+	}
+	for _, tt := range tests {
+		if got := UnmaskText(tt.input, mapping); got != tt.expected {
+			t.Errorf("UnmaskText(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestUnmaskTextManyTokens(t *testing.T) {
+	const n = 150
+	var keywords []string
+	var lines []string
+	for i := 1; i <= n; i++ {
+		kw := fmt.Sprintf("project%c%c", 'a'+i/26, 'a'+i%26)
+		keywords = append(keywords, kw)
+		lines = append(lines, fmt.Sprintf("host xy-node-%d at 10.%d.%d.%d owned by %s", i, i/250, i%250, i%200+1, kw))
+	}
+	input := strings.Join(lines, "\n")
+
+	m, err := NewMasker(Config{Keywords: keywords, HostnamePattern: `\bxy-[a-z0-9.-]+\b`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := m.Mask(input)
+	for _, prefix := range []string{"ip", "hostname", "kw"} {
+		if _, ok := result.Mapping[fmt.Sprintf("%s%d", prefix, n)]; !ok {
+			t.Fatalf("expected %d %s tokens, mapping has %d entries", n, prefix, len(result.Mapping))
+		}
+	}
+
+	// Repeat to catch map iteration order dependencies
+	for i := 0; i < 20; i++ {
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != input {
+			t.Fatalf("round trip failed on run %d:\n%s", i, firstDiff(got, input))
+		}
+	}
+}
+
+func firstDiff(got, want string) string {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := range wantLines {
+		if i >= len(gotLines) || gotLines[i] != wantLines[i] {
+			if i >= len(gotLines) {
+				return "missing line: " + wantLines[i]
+			}
+			return fmt.Sprintf("got:  %s\nwant: %s", gotLines[i], wantLines[i])
+		}
+	}
+	return "extra lines"
+}
+
+func TestUnmaskTextFuzzy(t *testing.T) {
+	mapping := map[string]string{
+		"ip1":       "192.168.1.50",
+		"hostname1": "xy-db",
+	}
+	tests := []struct {
+		input    string
+		expected string
+		reasons  []string
+	}{
+		{"restart IP1 now", "restart 192.168.1.50 now", []string{"case"}},
+		{"restart ip_1 now", "restart 192.168.1.50 now", []string{"separator"}},
+		{"`hostname1` is fine", "`xy-db` is fine", nil},
+		{"Hostname1's disk", "xy-db's disk", []string{"case"}},
+		{"both hostname1s", "both xy-dbs", []string{"plural"}},
+		{"curl ip1:8080", "curl 192.168.1.50:8080", nil},
+		{"ssh hostname1.example.com", "ssh xy-db.example.com", []string{"suffix"}},
+		{"zip1 and ip1x stay", "zip1 and ip1x stay", nil},
+	}
+	for _, tt := range tests {
+		got, report := UnmaskTextWithReport(tt.input, mapping, UnmaskOptions{Fuzzy: true})
+		if got != tt.expected {
+			t.Errorf("UnmaskTextWithReport(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+		if len(tt.reasons) == 0 {
+			if len(report.Fuzzy) != 0 {
+				t.Errorf("%q: unexpected fuzzy matches %+v", tt.input, report.Fuzzy)
+			}
+			continue
+		}
+		if len(report.Fuzzy) != 1 || strings.Join(report.Fuzzy[0].Reasons, ",") != strings.Join(tt.reasons, ",") {
+			t.Errorf("%q: fuzzy matches = %+v, want reasons %v", tt.input, report.Fuzzy, tt.reasons)
+		}
+	}
+
+	// Strict mode leaves mangled tokens alone
+	if got, _ := UnmaskTextWithReport("restart IP1 now", mapping, UnmaskOptions{}); got != "restart IP1 now" {
+		t.Errorf("strict UnmaskTextWithReport = %q", got)
+	}
+}
+
+func TestUnmaskTextFuzzyDelimiters(t *testing.T) {
+	scheme, err := NewTokenScheme("brackets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := map[string]string{"⟦IP_1⟧": "10.0.0.1"}
+	got, report := UnmaskTextWithReport("ping IP_1 and ⟦ip_1⟧ and ⟦IP_1⟧", mapping, UnmaskOptions{Fuzzy: true, Scheme: scheme})
+	if got != "ping 10.0.0.1 and 10.0.0.1 and 10.0.0.1" {
+		t.Errorf("UnmaskTextWithReport = %q", got)
+	}
+	if len(report.Fuzzy) != 2 || report.Fuzzy[0].Found != "IP_1" || report.Fuzzy[1].Found != "⟦ip_1⟧" {
+		t.Errorf("fuzzy matches = %+v", report.Fuzzy)
+	}
+}
+
+func TestUnmaskReport(t *testing.T) {
+	mapping := map[string]string{
+		"ip1":       "10.0.0.1",
+		"ip2":       "10.0.0.2",
+		"ip10":      "10.0.0.10",
+		"hostname1": "xy-db",
+	}
+	_, report := UnmaskTextWithReport("ip10 talks to hostname1 and ip7, utf8 is fine", mapping, UnmaskOptions{})
+
+	check := func(name string, got, want []string) {
+		t.Helper()
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	check("Restored", report.Restored, []string{"hostname1", "ip10"})
+	check("Missing", report.Missing, []string{"ip1", "ip2"})
+	check("Unknown", report.Unknown, []string{"ip7"})
+	if len(report.Warnings()) != 2 {
+		t.Errorf("Warnings = %v, want 2 lines", report.Warnings())
+	}
+}
+
+func TestUnmaskReportDelimitedUnknown(t *testing.T) {
+	scheme, err := NewTokenScheme("angle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := map[string]string{"<<IP_1>>": "10.0.0.1"}
+	got, report := UnmaskTextWithReport("<<IP_1>> and <<HOSTNAME_4>>", mapping, UnmaskOptions{Scheme: scheme})
+	if got != "10.0.0.1 and <<HOSTNAME_4>>" {
+		t.Errorf("UnmaskTextWithReport = %q", got)
+	}
+	if len(report.Unknown) != 1 || report.Unknown[0] != "<<HOSTNAME_4>>" {
+		t.Errorf("Unknown = %v", report.Unknown)
+	}
+}
@@ -0,0 +1,292 @@
+package safe_paste
+
+import (
+	"errors"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DetectorURL masks the sensitive components of URLs
+const DetectorURL = "url"
+
+// Host modes for URLConfig.Host
+const (
+	URLHostInternal = "internal" // hosts the hostname pattern or IP detectors would mask (default)
+	URLHostAll      = "all"      // every host
+	URLHostNone     = "none"     // no host
+)
+
+// URLConfig selects the URL components that are masked
+type URLConfig struct {
+	// Host is "internal" (default), "all" or "none"
+	Host string `json:"host,omitempty"`
+	// PathSegments are regexes; path segments matching one completely are masked (path1)
+	PathSegments []string `json:"path_segments,omitempty"`
+	// QueryParams are the query parameters whose values are masked (secret1),
+	// matched case-insensitively. Empty means defaultQueryParams.
+	QueryParams []string `json:"query_params,omitempty"`
+}
+
+// defaultQueryParams are query parameters that usually carry credentials
+var defaultQueryParams = []string{
+	"token", "access_token", "refresh_token", "id_token", "auth", "key", "api_key", "apikey",
+	"secret", "client_secret", "password", "pwd", "sig", "signature", "code", "session", "sessionid",
+}
+
+var (
+	urlRegex = regexp.MustCompile("\\b[a-zA-Z][a-zA-Z0-9+.-]*://[^\\s<>\"'`]+")
+
+	errURLHost = errors.New(`must be "internal", "all" or "none"`)
+)
+
+func init() {
+	// Below the secret rules, so webhooks and JWTs in URLs stay whole secrets
+	RegisterDetector(DetectorSpec{Name: DetectorURL, Priority: 505, New: newURLDetector})
+}
+
+// urlDetector splits URLs into components and masks userinfo, host, path
+// segments and query values independently. The rest of the URL is left to
+// the other detectors, so it stays a valid URL.
+type urlDetector struct {
+	cfg      URLConfig
+	hosts    *hostnameDetector
+	segments []*regexp.Regexp
+	params   map[string]bool
+	ipClass  map[string]bool // preserved IP classes
+
+	user, password, path, secret Detector
+	ipv4, ipv6                   Detector // the IP detectors, so hosts share their tokens, modes and preserve rules
+}
+
+func newURLDetector(cfg Config) (Detector, error) {
+	uc := cfg.URL
+	switch uc.Host {
+	case "":
+		uc.Host = URLHostInternal
+	case URLHostInternal, URLHostAll, URLHostNone:
+	default:
+		return nil, &ConfigError{Field: "url.host", Value: uc.Host, Err: errURLHost}
+	}
+	hosts, err := newHostnames(cfg)
+	if err != nil {
+		return nil, err
+	}
+	preserved, err := preservedIPClasses(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d := &urlDetector{cfg: uc, hosts: hosts, params: make(map[string]bool), ipClass: preserved}
+	for _, pattern := range uc.PathSegments {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, &ConfigError{Field: "url.path_segments", Value: pattern, Err: err}
+		}
+		d.segments = append(d.segments, re)
+	}
+	params := uc.QueryParams
+	if len(params) == 0 {
+		params = defaultQueryParams
+	}
+	for _, p := range params {
+		d.params[strings.ToLower(p)] = true
+	}
+
+	// Components share prefixes (and tokens) with the detectors of the same kind
+	d.user = &regexDetector{name: DetectorURL, prefix: "user", pseudonym: pseudonymName}
+	d.password = &regexDetector{name: DetectorURL, prefix: "secret"}
+	d.path = &regexDetector{name: DetectorURL, prefix: "path", pseudonym: pseudonymName}
+	d.secret = &regexDetector{name: DetectorURL, prefix: "secret"}
+	if d.ipv4, err = newIPv4Detector(cfg); err != nil {
+		return nil, err
+	}
+	if d.ipv6, err = newIPv6Detector(cfg); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *urlDetector) Name() string        { return DetectorURL }
+func (d *urlDetector) TokenPrefix() string { return "url" }
+
+func (d *urlDetector) FindMatches(text string) [][]int {
+	var matches [][]int
+	for _, m := range urlRegex.FindAllStringIndex(text, -1) {
+		matches = append(matches, []int{m[0], m[0] + len(trimURL(text[m[0]:m[1]]))})
+	}
+	return matches
+}
+
+func (d *urlDetector) Validate(candidate string) bool {
+	u, err := url.Parse(candidate)
+	return err == nil && u.Host != ""
+}
+
+// trimURL drops trailing punctuation that belongs to the sentence,
+// keeping closing brackets that have an opening one in the URL.
+func trimURL(s string) string {
+	for len(s) > 0 {
+		switch c := s[len(s)-1]; c {
+		case '.', ',', ';', ':', '!', '?', '\'', '"':
+		case ')':
+			if strings.Count(s, "(") >= strings.Count(s, ")") {
+				return s
+			}
+		case ']':
+			if strings.Count(s, "[") >= strings.Count(s, "]") {
+				return s
+			}
+		default:
+			return s
+		}
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// Split cuts the URL into scheme, userinfo, host, port, path, query and
+// fragment, without decoding anything, so the parts add up to the original.
+func (d *urlDetector) Split(raw string) []Part {
+	var parts []Part
+	keep := func(text string) {
+		if text != "" {
+			parts = append(parts, Part{Text: text, Open: true})
+		}
+	}
+	mask := func(text string, det Detector) {
+		if text != "" {
+			parts = append(parts, Part{Text: text, Detector: det})
+		}
+	}
+
+	i := strings.Index(raw, "://") + len("://")
+	keep(raw[:i])
+	rest := raw[i:]
+	authority := rest
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		authority, rest = rest[:end], rest[end:]
+	} else {
+		rest = ""
+	}
+
+	// user:password@
+	if at := strings.LastIndexByte(authority, '@'); at >= 0 {
+		userinfo := authority[:at]
+		if colon := strings.IndexByte(userinfo, ':'); colon >= 0 {
+			mask(userinfo[:colon], d.user)
+			keep(":")
+			mask(userinfo[colon+1:], d.password)
+		} else {
+			mask(userinfo, d.user)
+		}
+		keep("@")
+		authority = authority[at+1:]
+	}
+
+	// host[:port], with IPv6 hosts in brackets
+	host, port := authority, ""
+	if colon := strings.LastIndexByte(authority, ':'); colon >= 0 && colon > strings.LastIndexByte(authority, ']') {
+		host, port = authority[:colon], authority[colon:]
+	}
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		keep("[")
+		d.splitHost(host[1:len(host)-1], &parts)
+		keep("]")
+	} else {
+		d.splitHost(host, &parts)
+	}
+	keep(port)
+
+	// path, query and fragment
+	path, query, fragment := rest, "", ""
+	if hash := strings.IndexByte(path, '#'); hash >= 0 {
+		path, fragment = path[:hash], path[hash:]
+	}
+	if q := strings.IndexByte(path, '?'); q >= 0 {
+		path, query = path[:q], path[q:]
+	}
+	for i, segment := range strings.Split(path, "/") {
+		if i > 0 {
+			keep("/")
+		}
+		if d.maskSegment(segment) {
+			mask(segment, d.path)
+		} else {
+			keep(segment)
+		}
+	}
+	if query != "" {
+		keep("?")
+		for i, pair := range strings.Split(query[1:], "&") {
+			if i > 0 {
+				keep("&")
+			}
+			name, value, found := strings.Cut(pair, "=")
+			key, err := url.QueryUnescape(name)
+			if !found || err != nil || !d.params[strings.ToLower(key)] {
+				keep(pair)
+				continue
+			}
+			keep(name + "=")
+			mask(value, d.secret)
+		}
+	}
+	keep(fragment)
+	return parts
+}
+
+// splitHost adds the host part: masked, claimed as is, or open to other detectors
+func (d *urlDetector) splitHost(host string, parts *[]Part) {
+	if host == "" {
+		return
+	}
+	name, zone, _ := strings.Cut(host, "%25")
+	addr, err := netip.ParseAddr(name)
+	isIP := err == nil
+	var masked bool
+	switch d.cfg.Host {
+	case URLHostAll:
+		masked = true
+	case URLHostInternal:
+		if isIP {
+			masked = !d.ipClass[ipClass(addr)]
+		} else {
+			masked = d.hosts.hosts.internal(host)
+		}
+	}
+	switch {
+	case masked && isIP:
+		ip := d.ipv6
+		if addr.Is4() {
+			ip = d.ipv4
+		}
+		*parts = append(*parts, Part{Text: name, Detector: ip})
+		if zone != "" || strings.HasSuffix(host, "%25") {
+			*parts = append(*parts, Part{Text: host[len(name):], Open: true})
+		}
+	case masked:
+		// Hostnames are split like the hostname detector splits them
+		if split := d.hosts.Split(host); split != nil {
+			*parts = append(*parts, split...)
+		} else {
+			*parts = append(*parts, Part{Text: host, Detector: d.hosts})
+		}
+	case d.cfg.Host == URLHostNone:
+		*parts = append(*parts, Part{Text: host})
+	default:
+		*parts = append(*parts, Part{Text: host, Open: true})
+	}
+}
+
+func (d *urlDetector) maskSegment(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, re := range d.segments {
+		if re.MatchString(segment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package safe_paste
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DetectorUsername masks user names found in home directories, DOMAIN\user
+// and user=... assignments, and every other occurrence of those names
+const DetectorUsername = "username"
+
+// minUsernameLength is the shortest found name that is also masked where it
+// appears on its own, so short names do not mask ordinary words
+const minUsernameLength = 3
+
+var (
+	// /home/jdoe, /Users/jdoe, C:\Users\jdoe, C:/Users/jdoe (also with escaped backslashes)
+	homeDirRegex = regexp.MustCompile(`(?:/home/|/export/home/|/Users/|(?i:\b[a-z]:(?:\\+|/)Users(?:\\+|/)))(?P<value>[\w.-]*\w)`)
+	// CORP\jdoe: an upper case NetBIOS domain, one backslash or an escaped one
+	domainUserRegex = regexp.MustCompile(`\b[A-Z][A-Z0-9-]{1,14}\\{1,2}(?P<value>[A-Za-z][\w.-]*\w)`)
+	// user=jdoe, "username": "jdoe", LOGNAME=jdoe. Keys like "login" or "owner"
+	// are left out: "Login: success" is an outcome, not a name.
+	userKeyRegex = regexp.MustCompile(`(?i:\b(?:user(?:_?name)?|logname))["']?\s*[:=]\s*["']?(?P<value>[A-Za-z][\w.-]*\w)(?:$|[\s"',;&)\]}])`)
+)
+
+// logWords are words that follow "user:" in log messages rather than a name,
+// as in "user: Login failed". They are only skipped when capitalised, like prose.
+var logWords = map[string]bool{
+	"login": true, "logon": true, "logout": true, "logged": true, "authentication": true,
+	"authenticated": true, "auth": true, "access": true, "failed": true, "failure": true,
+	"success": true, "successful": true, "denied": true, "invalid": true, "unknown": true,
+	"not": true, "locked": true, "expired": true, "disabled": true, "enabled": true,
+	"created": true, "deleted": true, "added": true, "removed": true, "changed": true,
+	"found": true, "missing": true, "required": true, "error": true, "password": true,
+	"session": true, "account": true, "name": true, "id": true,
+}
+
+// systemUsers are built-in accounts and profile folders that identify nobody
+var systemUsers = map[string]bool{
+	"root": true, "nobody": true, "daemon": true, "system": true, "localsystem": true,
+	"localservice": true, "networkservice": true, "administrator": true, "guest": true,
+	"www-data": true, "public": true, "default": true, "shared": true, "all users": true,
+	"default user": true, "null": true, "none": true, "true": true, "false": true,
+}
+
+func init() {
+	// Above the hostname and keyword detectors, so a name is masked as a user everywhere
+	RegisterDetector(DetectorSpec{Name: DetectorUsername, Priority: 260, New: newUsernameDetector})
+}
+
+// usernameDetector finds user names by context, then masks them wherever they
+// appear. It shares the "user" prefix with email local parts and URL userinfo,
+// so the same name gets the same token from every detector.
+type usernameDetector struct {
+	regexDetector
+}
+
+func newUsernameDetector(cfg Config) (Detector, error) {
+	return &usernameDetector{regexDetector{name: DetectorUsername, prefix: "user", pseudonym: pseudonymName}}, nil
+}
+
+func (d *usernameDetector) FindMatches(text string) [][]int {
+	var matches [][]int
+	names := make(map[string]bool)
+	for _, re := range []*regexp.Regexp{homeDirRegex, domainUserRegex, userKeyRegex} {
+		values := &regexDetector{re: re}
+		for _, m := range values.FindMatches(text) {
+			if re == domainUserRegex && !isDomainUser(text, m[0], m[1]) {
+				continue
+			}
+			if re == userKeyRegex && isLogWord(text[m[0]:m[1]]) {
+				continue
+			}
+			matches = append(matches, m)
+			names[text[m[0]:m[1]]] = true
+		}
+	}
+
+	// Every other occurrence of a found name, as a whole word
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if len(name) >= minUsernameLength && d.Validate(name) {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		for offset := 0; ; {
+			i := strings.Index(text[offset:], name)
+			if i < 0 {
+				break
+			}
+			start, end := offset+i, offset+i+len(name)
+			if !isNamePart(text, start-1) && !isNamePart(text, end) {
+				matches = append(matches, []int{start, end})
+			}
+			offset = end
+		}
+	}
+	return matches
+}
+
+func (d *usernameDetector) Validate(candidate string) bool {
+	return !systemUsers[strings.ToLower(candidate)]
+}
+
+// isDomainUser rejects path components such as the System32 of C:\WINDOWS\System32:
+// the domain must start the path and the name must end it
+func isDomainUser(text string, start, end int) bool {
+	i := start
+	for i > 0 && text[i-1] == '\\' {
+		i--
+	}
+	for i > 0 && (text[i-1] >= 'A' && text[i-1] <= 'Z' || isDigit(text[i-1]) || text[i-1] == '-') {
+		i--
+	}
+	if i > 0 && strings.IndexByte(`\/:.`, text[i-1]) >= 0 {
+		return false
+	}
+	return end >= len(text) || text[end] != '\\' && text[end] != '/'
+}
+
+// isLogWord reports whether value is a capitalised word of a log message
+func isLogWord(value string) bool {
+	return value[0] >= 'A' && value[0] <= 'Z' && logWords[strings.ToLower(value)]
+}
+
+// isNamePart reports whether text[i] would continue a user name
+func isNamePart(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	return isWordByte(text[i]) || text[i] == '-' || text[i] == '.' && i+1 < len(text) && isWordByte(text[i+1])
+}
@@ -0,0 +1,56 @@
+package safe_paste
+
+import "testing"
+
+func TestMaskUsernames(t *testing.T) {
+	tests := []struct {
+		input  string
+		masked string
+	}{
+		{
+			"File \"/home/jdoe/app/main.py\", line 3; jdoe ran it, not jdoe2",
+			"File \"/home/user1/app/main.py\", line 3; user1 ran it, not jdoe2",
+		},
+		{`at C:\Users\asmith\AppData\Local and C:\\Users\\asmith\\x`, `at C:\Users\user1\AppData\Local and C:\\Users\\user1\\x`},
+		{`logon CORP\jdoe failed; C:\WINDOWS\System32 is fine`, `logon CORP\user1 failed; C:\WINDOWS\System32 is fine`},
+		{"USER=mkaya LOGNAME=mkaya sudo -u mkaya", "USER=user1 LOGNAME=user1 sudo -u user1"},
+		{`{"username": "e.yilmaz", "user": "root"}`, `{"username": "user1", "user": "root"}`},
+		{"/Users/Shared and /home/root stay", "/Users/Shared and /home/root stay"},
+		{"User: arn:aws:iam::123456789012:user/ops", "User: arn:aws:iam::account1:user/resource1"},
+	}
+	for _, tt := range tests {
+		result := maskWithConfig(t, Config{}, tt.input)
+		if result.MaskedText != tt.masked {
+			t.Errorf("Mask(%q) =\n%q, want\n%q", tt.input, result.MaskedText, tt.masked)
+		}
+		if got := UnmaskText(result.MaskedText, result.Mapping); got != tt.input {
+			t.Errorf("round trip of %q = %q", tt.input, got)
+		}
+	}
+}
+
+func TestUsernameIgnoresAuthResults(t *testing.T) {
+	inputs := []string{
+		"Login: success\nsession opened, login success for 10.0.0.5? no: success",
+		"login: failed (3 attempts), owner: unknown, failed again",
+		`{"event": "login", "login": "success", "owner": "team"}`,
+		"user: Login failed\nLogin retried by admin, User: Access denied",
+	}
+	for _, input := range inputs {
+		result := maskWithConfig(t, Config{}, input)
+		for _, span := range result.Spans {
+			if span.Detector == DetectorUsername {
+				t.Errorf("Mask(%q) masked %q as a user name", input, span.Original)
+			}
+		}
+	}
+}
+
+func TestUsernameSharesUserTokens(t *testing.T) {
+	cfg := Config{Profiles: []string{ProfileContact}, EmailMode: EmailLocal}
+	input := "ssh://jdoe@10.0.0.5 as /home/jdoe, mail jdoe@example.com"
+	want := "ssh://user1@ip1 as /home/user1, mail user1@example.com"
+	if got := maskWithConfig(t, cfg, input).MaskedText; got != want {
+		t.Errorf("Mask = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package safe_paste
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	vaultFile    = "vault.meta" // KDF parameters and passphrase check
+	vaultVersion = 1
+	vaultCheck   = "safepaste-vault"
+)
+
+var (
+	ErrWrongPassphrase = errors.New("wrong passphrase")
+	ErrEmptyPassphrase = errors.New("empty passphrase")
+)
+
+// vaultMeta is stored unencrypted next to the sessions
+type vaultMeta struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+	Check   []byte `json:"check"` // vaultCheck encrypted with the derived key
+}
+
+// Vault keeps sessions encrypted with AES-256-GCM, using a key derived from
+// a passphrase with Argon2id. The mappings never touch the disk in clear text.
+type Vault struct {
+	store *SessionStore
+}
+
+// VaultExists reports whether dir already holds a vault
+func VaultExists(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, vaultFile))
+	return err == nil
+}
+
+// OpenVault unlocks the vault in dir, creating it with this passphrase if it does not exist yet.
+// It returns ErrWrongPassphrase if the passphrase does not match.
+func OpenVault(dir, passphrase string) (*Vault, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+	metaPath := filepath.Join(dir, vaultFile)
+	data, err := os.ReadFile(metaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return createVault(dir, passphrase)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var meta vaultMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("vault %s: %w", metaPath, err)
+	}
+	if meta.Version != vaultVersion || meta.KDF != "argon2id" {
+		return nil, fmt.Errorf("vault %s: unsupported version %d (%s)", metaPath, meta.Version, meta.KDF)
+	}
+	aead, err := vaultCipher(passphrase, meta)
+	if err != nil {
+		return nil, err
+	}
+	check, err := decrypt(aead, meta.Check, vaultFile)
+	if err != nil || string(check) != vaultCheck {
+		return nil, ErrWrongPassphrase
+	}
+	return &Vault{store: &SessionStore{dir: dir, ext: ".enc", aead: aead}}, nil
+}
+
+func createVault(dir, passphrase string) (*Vault, error) {
+	meta := vaultMeta{
+		Version: vaultVersion,
+		KDF:     "argon2id",
+		Salt:    make([]byte, 16),
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+	if _, err := rand.Read(meta.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := vaultCipher(passphrase, meta)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Check, err = encrypt(aead, []byte(vaultCheck), vaultFile); err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, vaultFile), data, 0600); err != nil {
+		return nil, err
+	}
+	return &Vault{store: &SessionStore{dir: dir, ext: ".enc", aead: aead}}, nil
+}
+
+func vaultCipher(passphrase string, meta vaultMeta) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), meta.Salt, meta.Time, meta.Memory, meta.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns nonce || ciphertext. The name is authenticated so files cannot be swapped.
+func encrypt(aead cipher.AEAD, plaintext []byte, name string) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, []byte(name)), nil
+}
+
+func decrypt(aead cipher.AEAD, data []byte, name string) ([]byte, error) {
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("encrypted data too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(name))
+}
+
+func (v *Vault) SaveSession(sess Session) error {
+	return v.store.Save(sess)
+}
+
+func (v *Vault) LoadSession(name string) (Session, error) {
+	return v.store.Load(name)
+}
+
+// ListSessions returns all sessions, most recently updated first
+func (v *Vault) ListSessions() ([]Session, error) {
+	return v.store.List()
+}
+
+func (v *Vault) DeleteSession(name string) error {
+	return v.store.Delete(name)
+}
+
+// PruneSessions deletes sessions not updated within ttl
+func (v *Vault) PruneSessions(ttl time.Duration) (int, error) {
+	return v.store.Prune(ttl)
+}
+
+// Import moves the sessions of a plain store into the vault and deletes the clear text files
+func (v *Vault) Import(plain *SessionStore) (int, error) {
+	sessions, err := plain.List()
+	if err != nil {
+		return 0, err
+	}
+	for i, sess := range sessions {
+		if err := v.store.Save(sess); err != nil {
+			return i, err
+		}
+		if err := plain.Delete(sess.Name); err != nil {
+			return i, err
+		}
+	}
+	return len(sessions), nil
+}
@@ -0,0 +1,102 @@
+package safe_paste
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVaultRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	if VaultExists(dir) {
+		t.Fatal("VaultExists before creation")
+	}
+	vault, err := OpenVault(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping := map[string]string{"ip1": "192.168.1.100", "hostname1": "xy-server"}
+	if err := vault.SaveSession(Session{Name: "incident", Mapping: mapping}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "incident.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("192.168.1.100")) || bytes.Contains(data, []byte("xy-server")) {
+		t.Error("session file contains clear text values")
+	}
+
+	reopened, err := OpenVault(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess, err := reopened.LoadSession("incident")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for masked, original := range mapping {
+		if sess.Mapping[masked] != original {
+			t.Errorf("Mapping[%v] = %v, want %v", masked, sess.Mapping[masked], original)
+		}
+	}
+	sessions, err := reopened.ListSessions()
+	if err != nil || len(sessions) != 1 {
+		t.Errorf("ListSessions = %+v, %v", sessions, err)
+	}
+}
+
+func TestVaultWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := OpenVault(dir, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenVault(dir, "not the secret"); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("OpenVault error = %v, want ErrWrongPassphrase", err)
+	}
+	if _, err := OpenVault(dir, ""); !errors.Is(err, ErrEmptyPassphrase) {
+		t.Errorf("OpenVault error = %v, want ErrEmptyPassphrase", err)
+	}
+}
+
+func TestVaultRejectsSwappedFiles(t *testing.T) {
+	dir := t.TempDir()
+	vault, err := OpenVault(dir, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vault.SaveSession(Session{Name: "a", Mapping: map[string]string{"ip1": "10.0.0.1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "a.enc"), filepath.Join(dir, "b.enc")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := vault.LoadSession("b"); err == nil {
+		t.Error("LoadSession accepted a renamed session file")
+	}
+}
+
+func TestVaultImport(t *testing.T) {
+	dir := t.TempDir()
+	plain := NewSessionStore(dir)
+	if err := plain.Save(Session{Name: "old", Mapping: map[string]string{"ip1": "10.0.0.1"}}); err != nil {
+		t.Fatal(err)
+	}
+	vault, err := OpenVault(dir, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := vault.Import(plain); err != nil || n != 1 {
+		t.Fatalf("Import = %d, %v, want 1", n, err)
+	}
+	if sessions, _ := plain.List(); len(sessions) != 0 {
+		t.Errorf("plain sessions left after Import: %+v", sessions)
+	}
+	sess, err := vault.LoadSession("old")
+	if err != nil || sess.Mapping["ip1"] != "10.0.0.1" {
+		t.Errorf("LoadSession = %+v, %v", sess, err)
+	}
+}